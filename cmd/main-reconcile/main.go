@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/databases"
+	"github.com/riskibarqy/bq-account-service/external/clerk"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	appPg "github.com/riskibarqy/bq-account-service/internal/repository/app"
+	userPg "github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/reconcile"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+)
+
+// Reconciles the user table with Clerk.
+//
+//	go run ./cmd/main-reconcile                  # dry run, prints the drift report
+//	go run ./cmd/main-reconcile -apply           # fixes the drift
+//	go run ./cmd/main-reconcile -apply -every 1h # keeps running on a schedule
+func main() {
+	apply := flag.Bool("apply", false, "fix the drift instead of only reporting it")
+	batchSize := flag.Int("batch-size", 100, "users per Clerk page and per apply transaction")
+	every := flag.Duration("every", 0, "run on this interval until interrupted, 0 runs once")
+	flag.Parse()
+	if *batchSize <= 0 {
+		log.Fatal("batch-size must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	config.GetConfiguration()
+	databases.Init()
+	defer config.AppConfig.DatabaseClient.Close()
+	redis.Init(ctx)
+	clerk.Init()
+
+	db := config.AppConfig.DatabaseClient
+	dataManager := data.NewManager(db)
+	userPostgresStorage := userPg.NewUserRepository(
+		data.NewPostgresStorage(db, "user", models.User{}),
+	)
+	appPostgresStorage := appPg.NewAppRepository(
+		data.NewPostgresStorage(db, "app", models.App{}),
+	)
+	identityProviders := identity.Providers{
+		identity.ProviderClerk: identity.NewClerkProvider(),
+	}
+	// The reconciliation never registers users or sets passwords, so it has no password policy
+	reconcileService := reconcile.NewReconcileService(
+		dataManager,
+		userPostgresStorage,
+		user.NewUserService(userPostgresStorage, appPostgresStorage, identityProviders, nil),
+		*batchSize,
+	)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	for {
+		report, err := reconcileService.Reconcile(ctx, *apply)
+		if err != nil {
+			log.Printf("Reconciliation failed: %s%s", err.Message, err.Path)
+		} else if errEncode := encoder.Encode(report); errEncode != nil {
+			log.Printf("Failed to write report: %v", errEncode)
+		}
+
+		if *every <= 0 {
+			if err != nil || (report != nil && len(report.Errors) > 0) {
+				os.Exit(1)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*every):
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"strings"
 
 	"github.com/ancalabrese/reload"
 	"github.com/jmoiron/sqlx"
@@ -11,29 +12,203 @@ import (
 	"github.com/riskibarqy/bq-account-service/databases"
 	"github.com/riskibarqy/bq-account-service/external/clerk"
 	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/external/mailer"
 	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/external/sms"
 	"github.com/riskibarqy/bq-account-service/internal/data"
 	internalhttp "github.com/riskibarqy/bq-account-service/internal/http"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
 	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/password"
+	appPg "github.com/riskibarqy/bq-account-service/internal/repository/app"
+	appRedirectURIPg "github.com/riskibarqy/bq-account-service/internal/repository/appredirecturi"
+	mfaRecoveryCodePg "github.com/riskibarqy/bq-account-service/internal/repository/mfarecoverycode"
+	oauthConsentPg "github.com/riskibarqy/bq-account-service/internal/repository/oauthconsent"
+	passwordResetTokenPg "github.com/riskibarqy/bq-account-service/internal/repository/passwordresettoken"
+	refreshTokenPg "github.com/riskibarqy/bq-account-service/internal/repository/refreshtoken"
+	securityEventPg "github.com/riskibarqy/bq-account-service/internal/repository/securityevent"
 	userPg "github.com/riskibarqy/bq-account-service/internal/repository/user"
+	userMFAPg "github.com/riskibarqy/bq-account-service/internal/repository/usermfa"
+	userPasswordPg "github.com/riskibarqy/bq-account-service/internal/repository/userpassword"
+	webAuthnCredentialPg "github.com/riskibarqy/bq-account-service/internal/repository/webauthncredential"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/app"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/auth"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/lockout"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/mfa"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/oauth"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/passkey"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/token"
 	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/verification"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
 )
 
 var ctx = context.Background()
 
 // InternalServices represents all the internal domain services
 type InternalServices struct {
-	userService user.ServiceInterface
+	userService    user.ServiceInterface
+	tokenService   token.ServiceInterface
+	sessionService session.ServiceInterface
+	authService    auth.ServiceInterface
+
+	verificationService verification.ServiceInterface
+	mfaService          mfa.ServiceInterface
+	passkeyService      passkey.ServiceInterface
+	lockoutService      lockout.ServiceInterface
+	appService          app.AppServiceInterface
+	oauthService        oauth.ServiceInterface
 }
 
-func buildInternalServices(db *sqlx.DB, _ *config.Config) *InternalServices {
+func buildInternalServices(db *sqlx.DB, dataManager *data.Manager, cfg *config.Config) *InternalServices {
 	userPostgresStorage := userPg.NewUserRepository(
 		data.NewPostgresStorage(db, "user", models.User{}),
 	)
+	refreshTokenPostgresStorage := refreshTokenPg.NewRefreshTokenRepository(
+		data.NewPostgresStorage(db, "refresh_token", models.RefreshToken{}),
+	)
+
+	appPostgresStorage := appPg.NewAppRepository(
+		data.NewPostgresStorage(db, "app", models.App{}),
+	)
+	userPasswordPostgresStorage := userPasswordPg.NewUserPasswordRepository(
+		data.NewPostgresStorage(db, "user_password", models.UserPassword{}),
+	)
+	passwordResetTokenPostgresStorage := passwordResetTokenPg.NewPasswordResetTokenRepository(
+		data.NewPostgresStorage(db, "password_reset_token", models.PasswordResetToken{}),
+	)
+	securityEventPostgresStorage := securityEventPg.NewSecurityEventRepository(
+		data.NewPostgresStorage(db, "security_event", models.SecurityEvent{}),
+	)
+	userMFAPostgresStorage := userMFAPg.NewUserMFARepository(
+		data.NewPostgresStorage(db, "user_mfa", models.UserMFA{}),
+	)
+	mfaRecoveryCodePostgresStorage := mfaRecoveryCodePg.NewMFARecoveryCodeRepository(
+		data.NewPostgresStorage(db, "mfa_recovery_code", models.MFARecoveryCode{}),
+	)
+	webAuthnCredentialPostgresStorage := webAuthnCredentialPg.NewWebAuthnCredentialRepository(
+		data.NewPostgresStorage(db, "webauthn_credential", models.WebAuthnCredential{}),
+	)
+	appRedirectURIPostgresStorage := appRedirectURIPg.NewAppRedirectURIRepository(
+		data.NewPostgresStorage(db, "app_redirect_uri", models.AppRedirectURI{}),
+	)
+	oauthConsentPostgresStorage := oauthConsentPg.NewOAuthConsentRepository(
+		data.NewPostgresStorage(db, "oauth_consent", models.OAuthConsent{}),
+	)
+
+	passwordHasher, err := password.NewHasher(cfg.PasswordHasher, &password.Argon2idParams{
+		Memory:      uint32(cfg.Argon2idMemory),
+		Iterations:  uint32(cfg.Argon2idIterations),
+		Parallelism: uint8(cfg.Argon2idParallelism),
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	passwordPolicy := &password.Policy{
+		MinLength:           cfg.PasswordMinLength,
+		MinCharacterClasses: cfg.PasswordMinCharacterClasses,
+	}
+	if cfg.PasswordBreachedList != "" {
+		passwordPolicy.Breached, err = password.LoadBreachedList(cfg.PasswordBreachedList)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	localProvider, err := identity.NewLocalProvider(userPostgresStorage, userPasswordPostgresStorage, passwordHasher)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	identityProviders := identity.Providers{
+		identity.ProviderClerk: identity.NewClerkProvider(),
+		identity.ProviderLocal: localProvider,
+	}
+	if cfg.AppMode == "development" {
+		identityProviders[identity.ProviderMemory] = identity.NewMemoryProvider()
+	}
+
+	userService := user.NewUserService(userPostgresStorage, appPostgresStorage, identityProviders, passwordPolicy)
+	sessionService := session.NewSessionService()
+	tokenService := token.NewTokenService(dataManager, refreshTokenPostgresStorage, userPostgresStorage, sessionService)
+	var mailSender mailer.Sender
+	switch cfg.MailSender {
+	case mailer.SenderSMTP:
+		mailSender = mailer.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case mailer.SenderFile:
+		mailSender = mailer.NewFileSender(cfg.MailOutboxDir, cfg.MailFrom)
+	default:
+		log.Fatalf("unknown mail sender %q", cfg.MailSender)
+	}
+	var smsSender sms.Sender
+	switch cfg.SMSSender {
+	case sms.SenderLog:
+		smsSender = sms.NewLogSender()
+	default:
+		log.Fatalf("unknown SMS sender %q", cfg.SMSSender)
+	}
+	verificationService := verification.NewVerificationService(userService, mailSender, smsSender)
+
+	auditService := audit.NewAuditService(securityEventPostgresStorage)
+	appService := app.NewService(appPostgresStorage, appRedirectURIPostgresStorage, identityProviders, auditService)
+	lockoutService := lockout.NewLockoutService(userPostgresStorage, auditService)
+	mfaService := mfa.NewMFAService(
+		userPostgresStorage,
+		identityProviders,
+		userMFAPostgresStorage,
+		mfaRecoveryCodePostgresStorage,
+		auditService,
+		lockoutService,
+		cfg.MFAEncryptionKey,
+	)
+	passkeyService := passkey.NewPasskeyService(
+		userPostgresStorage,
+		webAuthnCredentialPostgresStorage,
+		auditService,
+		&webauthn.RelyingParty{
+			ID:      cfg.WebAuthnRPID,
+			Name:    cfg.WebAuthnRPName,
+			Origins: strings.Split(cfg.WebAuthnOrigins, ","),
+			Timeout: cfg.WebAuthnTimeout * 1000,
+		},
+	)
+
+	authService := auth.NewAuthService(
+		userPostgresStorage,
+		identityProviders,
+		sessionService,
+		tokenService,
+		passwordPolicy,
+		passwordResetTokenPostgresStorage,
+		mailSender,
+		auditService,
+		mfaService,
+		passkeyService,
+		lockoutService,
+	)
+	oauthService := oauth.NewOAuthService(
+		appService,
+		appRedirectURIPostgresStorage,
+		oauthConsentPostgresStorage,
+		userPostgresStorage,
+		sessionService,
+		auditService,
+	)
 
-	userService := user.NewUserService(userPostgresStorage)
 	return &InternalServices{
-		userService: userService,
+		userService:    userService,
+		tokenService:   tokenService,
+		sessionService: sessionService,
+		authService:    authService,
+
+		verificationService: verificationService,
+		mfaService:          mfaService,
+		passkeyService:      passkeyService,
+		lockoutService:      lockoutService,
+		appService:          appService,
+		oauthService:        oauthService,
 	}
 }
 
@@ -68,6 +243,7 @@ func initMetadataConfig() {
 func main() {
 	go initMetadataConfig()
 	config.GetConfiguration()
+	config.InitKeySet()
 
 	databases.Init()
 	defer func() {
@@ -86,12 +262,21 @@ func main() {
 	log.Printf("Running in %s mode\n", config.AppConfig.AppMode)
 
 	dataManager := data.NewManager(config.AppConfig.DatabaseClient)
-	internalServices := buildInternalServices(config.AppConfig.DatabaseClient, config.AppConfig)
+	internalServices := buildInternalServices(config.AppConfig.DatabaseClient, dataManager, config.AppConfig)
 
 	s := internalhttp.NewServer(
 		config.AppConfig,
 		dataManager,
 		internalServices.userService,
+		internalServices.tokenService,
+		internalServices.sessionService,
+		internalServices.authService,
+		internalServices.verificationService,
+		internalServices.mfaService,
+		internalServices.passkeyService,
+		internalServices.lockoutService,
+		internalServices.appService,
+		internalServices.oauthService,
 	)
 
 	s.Serve()
@@ -1,36 +1,363 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
 )
 
 // Define a struct for the JWT claims (you can customize this as needed)
 type Claims struct {
-	ID int `json:"id"`
+	ID        int    `json:"id"`
+	SessionID string `json:"sid,omitempty"`
+
+	// AppID, ClientID and Scope are only set on tokens a user granted to an app
+	AppID    int    `json:"app_id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateJWTToken(user *models.User) (string, error) {
-	// Set the claims
-	claims := Claims{
-		ID: user.ID,
+// HasScope tells whether the token was issued with the scope
+func (c *Claims) HasScope(scope string) bool {
+	return hasScope(c.Scope, scope)
+}
+
+func GenerateJWTToken(user *models.User, sessionID string) (string, error) {
+	return generateUserToken(Claims{
+		ID:        user.ID,
+		SessionID: sessionID,
+	})
+}
+
+// GenerateDelegatedToken signs an access token a user granted to an app through the authorization code flow.
+// It carries the app and the granted scopes, and is only accepted on the routes that take one of them.
+func GenerateDelegatedToken(user *models.User, sessionID string, app *models.App, scopes []string) (string, error) {
+	return generateUserToken(Claims{
+		ID:        user.ID,
+		SessionID: sessionID,
+		AppID:     app.ID,
+		ClientID:  app.ClientID,
+		Scope:     strings.Join(scopes, " "),
+	})
+}
+
+// generateUserToken fills in the standard claims of a user access token and signs it
+func generateUserToken(claims Claims) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.StandardClaims = jwt.StandardClaims{
+		Audience:  AppConfig.JWTAudience,
+		ExpiresAt: now.Add(time.Duration(AppConfig.AccessTokenTTL) * time.Second).Unix(),
+		Id:        tokenID,
+		IssuedAt:  now.Unix(),
+		Issuer:    AppConfig.JWTIssuer,
+		Subject:   strconv.Itoa(claims.ID),
+	}
+
+	return signToken(claims)
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token, telling an app who signed in, when and how
+type IDTokenClaims struct {
+	Nonce       string   `json:"nonce,omitempty"`
+	AuthTime    int64    `json:"auth_time"`
+	AuthMethods []string `json:"amr,omitempty"`
+	jwt.StandardClaims
+}
+
+// GenerateIDToken signs an ID token for the app the user signed in to. It is issued by OIDC_ISSUER to the
+// client id, so it doesn't pass as an access token of this service.
+func GenerateIDToken(user *models.User, app *models.App, nonce string, authTime int, authMethods []string) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return signToken(IDTokenClaims{
+		Nonce:       nonce,
+		AuthTime:    int64(authTime),
+		AuthMethods: authMethods,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 72).Unix(), // 72-hour expiration
-			Issuer:    "account-app",
+			Audience:  app.ClientID,
+			ExpiresAt: now.Add(time.Duration(AppConfig.AccessTokenTTL) * time.Second).Unix(),
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			Issuer:    AppConfig.OIDCIssuer,
+			Subject:   strconv.Itoa(user.ID),
 		},
+	})
+}
+
+// EmailVerificationClaims are the claims of an email verification token. The email is
+// part of the token so changing it retires the links sent to the old address.
+type EmailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+// emailVerificationAudience keeps verification tokens from passing as access tokens and the other way around
+const emailVerificationAudience = "email-verification"
+
+// GenerateEmailVerificationToken signs a token that verifies the current email of the user
+func GenerateEmailVerificationToken(user *models.User, ttl time.Duration) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return signToken(EmailVerificationClaims{
+		Email: user.Email,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  emailVerificationAudience,
+			ExpiresAt: now.Add(ttl).Unix(),
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			Issuer:    AppConfig.JWTIssuer,
+			Subject:   strconv.Itoa(user.ID),
+		},
+	})
+}
+
+// ParseEmailVerificationToken verifies an email verification token and returns its user ID and email,
+// the returned error is one of the ParseJWTToken errors
+func ParseEmailVerificationToken(tokenString string) (int, string, error) {
+	claims := &EmailVerificationClaims{}
+	err := parseToken(tokenString, claims)
+	if err != nil {
+		return 0, "", err
+	}
+
+	userID, errConversion := strconv.Atoi(claims.Subject)
+	if errConversion != nil || userID == 0 || claims.Email == "" || claims.ExpiresAt == 0 {
+		return 0, "", types.ErrTokenInvalid
+	}
+
+	if !claims.VerifyIssuer(AppConfig.JWTIssuer, true) || !claims.VerifyAudience(emailVerificationAudience, true) {
+		return 0, "", types.ErrTokenInvalid
+	}
+
+	return userID, claims.Email, nil
+}
+
+// MFAClaims are the claims of an MFA pending token, handed out by a login that passed the
+// first factor and exchanged for a session once the second factor checks out. AuthMethods
+// are the methods of the first factor, which the session records along with the second.
+type MFAClaims struct {
+	AuthMethods []string `json:"amr"`
+	jwt.StandardClaims
+}
+
+// mfaAudience keeps MFA pending tokens from passing as access tokens and the other way around
+const mfaAudience = "mfa-pending"
+
+// GenerateMFAToken signs an MFA pending token for the user and returns it with its token ID
+func GenerateMFAToken(userID int, authMethods []string, ttl time.Duration) (string, string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", "", err
 	}
 
-	// Create the token with the specified claims and sign it with the secret key
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	now := time.Now()
+	token, err := signToken(MFAClaims{
+		AuthMethods: authMethods,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  mfaAudience,
+			ExpiresAt: now.Add(ttl).Unix(),
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			Issuer:    AppConfig.JWTIssuer,
+			Subject:   strconv.Itoa(userID),
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, tokenID, nil
+}
+
+// ParseMFAToken verifies an MFA pending token and returns its user ID, token ID and the methods
+// of the first factor, the returned error is one of the ParseJWTToken errors
+func ParseMFAToken(tokenString string) (int, string, []string, error) {
+	claims := &MFAClaims{}
+	err := parseToken(tokenString, claims)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	userID, errConversion := strconv.Atoi(claims.Subject)
+	if errConversion != nil || userID == 0 || claims.Id == "" || claims.ExpiresAt == 0 {
+		return 0, "", nil, types.ErrTokenInvalid
+	}
+
+	if !claims.VerifyIssuer(AppConfig.JWTIssuer, true) || !claims.VerifyAudience(mfaAudience, true) {
+		return 0, "", nil, types.ErrTokenInvalid
+	}
+
+	return userID, claims.Id, claims.AuthMethods, nil
+}
+
+// ClientClaims are the claims of an access token issued to an app rather than a user. The subject is
+// the client id, so the token can't pass for one of a user with the same numeric ID.
+type ClientClaims struct {
+	AppID    int    `json:"app_id"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.StandardClaims
+}
+
+// HasScope tells whether the token was issued with the scope
+func (c *ClientClaims) HasScope(scope string) bool {
+	return hasScope(c.Scope, scope)
+}
+
+// hasScope tells whether the space separated scopes hold the scope
+func hasScope(scopes string, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateClientToken signs an access token for the app carrying the space separated scopes
+func GenerateClientToken(app *models.App, scopes []string) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return signToken(ClientClaims{
+		AppID:    app.ID,
+		ClientID: app.ClientID,
+		Scope:    strings.Join(scopes, " "),
+		StandardClaims: jwt.StandardClaims{
+			Audience:  AppConfig.JWTAudience,
+			ExpiresAt: now.Add(time.Duration(AppConfig.AccessTokenTTL) * time.Second).Unix(),
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			Issuer:    AppConfig.JWTIssuer,
+			Subject:   app.ClientID,
+		},
+	})
+}
+
+// ParseClientToken verifies an access token issued to an app and returns its claims,
+// the returned error is one of the ParseJWTToken errors
+func ParseClientToken(tokenString string) (*ClientClaims, error) {
+	claims := &ClientClaims{}
+	err := parseToken(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.AppID == 0 || claims.ClientID == "" || claims.Subject != claims.ClientID || claims.ExpiresAt == 0 {
+		return nil, types.ErrTokenInvalid
+	}
+
+	if !claims.VerifyIssuer(AppConfig.JWTIssuer, true) || !claims.VerifyAudience(AppConfig.JWTAudience, true) {
+		return nil, types.ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// signToken signs the claims with the current signing key
+func signToken(claims jwt.Claims) (string, error) {
+	signer := JWTKeySet.Signer()
+	if signer == nil {
+		return "", fmt.Errorf("no JWT signing key configured")
+	}
+	token := jwt.NewWithClaims(signer.Method, claims)
+	token.Header["kid"] = signer.ID
 
 	// Generate the signed token string
-	signedToken, err := token.SignedString([]byte(AppConfig.JWTSecret))
+	signedToken, err := token.SignedString(signer.Private)
 	if err != nil {
 		return "", err
 	}
 
 	return signedToken, nil
 }
+
+// ParseJWTToken verifies the signature, expiry, issuer and audience of the token
+// and returns its claims. The returned error is one of types.ErrTokenExpired,
+// types.ErrTokenMalformed or types.ErrTokenInvalid.
+func ParseJWTToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	err := parseToken(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ID == 0 || claims.ExpiresAt == 0 {
+		return nil, types.ErrTokenInvalid
+	}
+
+	if !claims.VerifyIssuer(AppConfig.JWTIssuer, true) || !claims.VerifyAudience(AppConfig.JWTAudience, true) {
+		return nil, types.ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// parseToken verifies the signature and expiry of the token into claims
+func parseToken(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key := JWTKeySet.Key(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		// Pin the algorithm to the key so a token can't pick its own verification method
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key.Public, nil
+	})
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok {
+			switch {
+			case ve.Errors&jwt.ValidationErrorMalformed != 0:
+				return types.ErrTokenMalformed
+			case ve.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+				return types.ErrTokenInvalid
+			case ve.Errors&jwt.ValidationErrorExpired != 0:
+				return types.ErrTokenExpired
+			}
+		}
+		return types.ErrTokenInvalid
+	}
+
+	if !token.Valid {
+		return types.ErrTokenInvalid
+	}
+
+	return nil
+}
+
+// generateTokenID returns a random identifier for the "jti" claim
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
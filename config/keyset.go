@@ -0,0 +1,217 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is a single asymmetric key of the JWT key set
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Private is nil for retiring keys that were loaded from a public key only
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	// Active keys may sign new tokens, retiring keys are only used for verification
+	Active bool
+}
+
+// JSONWebKey represents a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet represents the JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet holds every key used to sign or verify our JWTs
+type KeySet struct {
+	keys   map[string]*SigningKey
+	signer *SigningKey
+}
+
+var JWTKeySet = &KeySet{keys: map[string]*SigningKey{}}
+
+// Signer returns the key new tokens are signed with
+func (ks *KeySet) Signer() *SigningKey {
+	return ks.signer
+}
+
+// Key returns the key with the given kid, or nil when it is unknown
+func (ks *KeySet) Key(kid string) *SigningKey {
+	return ks.keys[kid]
+}
+
+// JWKS returns the public part of every active and retiring key
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JSONWebKey{
+			Use: "sig",
+			Kid: key.ID,
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// InitKeySet loads the JWT key set from JWT_KEYS_DIR.
+// Every "<kid>.pem" file in the directory becomes a key. Keys listed in
+// JWT_ACTIVE_KEY_IDS are active and the first one signs new tokens, every other
+// key is retiring: it still verifies tokens and is published in the JWKS until
+// its file is removed.
+func InitKeySet() {
+	if AppConfig.JWTKeysDir == "" {
+		if AppConfig.AppMode != "development" {
+			log.Fatal("[JWT] JWT_KEYS_DIR is required outside development mode")
+		}
+
+		// Development convenience, tokens won't survive a restart
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			log.Fatalf("[JWT] Failed to generate ephemeral signing key: %v", err)
+		}
+
+		key := &SigningKey{
+			ID:      "dev",
+			Method:  jwt.SigningMethodRS256,
+			Private: privateKey,
+			Public:  &privateKey.PublicKey,
+			Active:  true,
+		}
+		JWTKeySet = &KeySet{keys: map[string]*SigningKey{key.ID: key}, signer: key}
+		log.Println("[JWT] JWT_KEYS_DIR is not set, using an ephemeral RS256 key")
+		return
+	}
+
+	activeKeyIDs := []string{}
+	for _, kid := range strings.Split(AppConfig.JWTActiveKeyIDs, ",") {
+		if kid = strings.TrimSpace(kid); kid != "" {
+			activeKeyIDs = append(activeKeyIDs, kid)
+		}
+	}
+
+	keySet, err := loadKeySet(AppConfig.JWTKeysDir, activeKeyIDs)
+	if err != nil {
+		log.Fatalf("[JWT] Failed to load key set: %v", err)
+	}
+	JWTKeySet = keySet
+
+	log.Printf("[JWT] Loaded %d key(s), signing with kid %q", len(keySet.keys), keySet.signer.ID)
+}
+
+func loadKeySet(dir string, activeKeyIDs []string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &KeySet{keys: map[string]*SigningKey{}}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadSigningKey(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		keySet.keys[kid] = key
+	}
+
+	for _, kid := range activeKeyIDs {
+		key, ok := keySet.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("active key %q not found in %s", kid, dir)
+		}
+		if key.Private == nil {
+			return nil, fmt.Errorf("active key %q has no private key", kid)
+		}
+
+		key.Active = true
+		if keySet.signer == nil {
+			keySet.signer = key
+		}
+	}
+
+	if keySet.signer == nil {
+		return nil, fmt.Errorf("JWT_ACTIVE_KEY_IDS must name at least one key")
+	}
+
+	return keySet, nil
+}
+
+func loadSigningKey(kid string, path string) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		key.Private, key.Public = privateKey, &privateKey.PublicKey
+	} else if privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes); err == nil {
+		key.Private, key.Public = privateKey, &privateKey.PublicKey
+	} else if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		key.Public = publicKey
+	} else if publicKey, err := jwt.ParseECPublicKeyFromPEM(pemBytes); err == nil {
+		key.Public = publicKey
+	} else {
+		return nil, fmt.Errorf("not a PEM encoded RSA or EC key")
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s, only P-256 (ES256) is supported", pub.Curve.Params().Name)
+		}
+		key.Method = jwt.SigningMethodES256
+	}
+
+	return key, nil
+}
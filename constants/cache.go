@@ -1 +1,85 @@
 package constants
+
+// Redis keys, formatted with fmt.Sprintf
+const (
+	// UserCacheKey holds a single user, by user ID
+	UserCacheKey = "GetUser-%d"
+
+	// ListUsersCacheKeyPrefix prefixes every cached user list, the list count is cached under "cnt-" + key
+	ListUsersCacheKeyPrefix = "ListUsers-"
+
+	// AppCacheKey holds a single app, by app ID
+	AppCacheKey = "GetApp-%d"
+
+	// ListAppsCacheKeyPrefix prefixes every cached app list, the list count is cached under "cnt-" + key
+	ListAppsCacheKeyPrefix = "ListApps-"
+
+	// SessionCacheKey holds a single session, by session ID
+	SessionCacheKey = "Session-%s"
+
+	// UserSessionsCacheKey holds the set of session IDs of a user, by user ID
+	UserSessionsCacheKey = "UserSessions-%d"
+
+	// ClerkWebhookCacheKey marks a Clerk webhook delivery as processed, by svix-id
+	ClerkWebhookCacheKey = "ClerkWebhook-%s"
+
+	// EmailVerificationResendCacheKey throttles resending the verification email, by user ID
+	EmailVerificationResendCacheKey = "EmailVerificationResend-%d"
+
+	// PhoneOTPCacheKey holds the hashed phone verification code, by user ID
+	PhoneOTPCacheKey = "PhoneOTP-%d"
+
+	// PhoneOTPAttemptsCacheKey counts the wrong guesses of the phone verification code, by user ID
+	PhoneOTPAttemptsCacheKey = "PhoneOTPAttempts-%d"
+
+	// PhoneOTPResendCacheKey throttles sending phone verification codes, by user ID
+	PhoneOTPResendCacheKey = "PhoneOTPResend-%d"
+
+	// PasswordResetCacheKey throttles sending password reset emails, by user ID
+	PasswordResetCacheKey = "PasswordReset-%d"
+
+	// MFAAttemptsCacheKey counts the wrong second factor codes sent with an MFA pending token, by token ID
+	MFAAttemptsCacheKey = "MFAAttempts-%s"
+
+	// MFATokenUsedCacheKey marks an MFA pending token as exchanged, by token ID
+	MFATokenUsedCacheKey = "MFATokenUsed-%s"
+
+	// WebAuthnRegistrationCacheKey holds the challenge of a pending passkey registration, by user ID
+	WebAuthnRegistrationCacheKey = "WebAuthnRegistration-%d"
+
+	// WebAuthnLoginCacheKey marks a passkey login challenge as issued, by challenge
+	WebAuthnLoginCacheKey = "WebAuthnLogin-%s"
+
+	// EmailLoginCacheKey holds the hashed link token and code of a pending email login, by hashed browser nonce
+	EmailLoginCacheKey = "EmailLogin-%s"
+
+	// EmailLoginAttemptsCacheKey counts the wrong links and codes sent for a pending email login, by hashed browser nonce
+	EmailLoginAttemptsCacheKey = "EmailLoginAttempts-%s"
+
+	// EmailLoginResendCacheKey throttles sending login emails to a browser, by user ID and hashed browser nonce
+	EmailLoginResendCacheKey = "EmailLoginResend-%d-%s"
+
+	// EmailLoginUserCacheKey counts the login emails sent to a user, by user ID
+	EmailLoginUserCacheKey = "EmailLoginUser-%d"
+
+	// LoginFailuresAccountCacheKey counts the failed logins of an account, by hashed email
+	LoginFailuresAccountCacheKey = "LoginFailuresAccount-%s"
+
+	// LoginFailuresIPCacheKey counts the failed logins from an IP
+	LoginFailuresIPCacheKey = "LoginFailuresIP-%s"
+
+	// LoginDelayAccountCacheKey holds back the next login of an account until it expires, by hashed email
+	LoginDelayAccountCacheKey = "LoginDelayAccount-%s"
+
+	// LoginDelayIPCacheKey holds back the next login from an IP until it expires
+	LoginDelayIPCacheKey = "LoginDelayIP-%s"
+
+	// LoginLockAccountCacheKey locks an account out of logging in until it expires, by hashed email
+	LoginLockAccountCacheKey = "LoginLockAccount-%s"
+
+	// LoginLockIPCacheKey locks an IP out of logging in until it expires
+	LoginLockIPCacheKey = "LoginLockIP-%s"
+
+	// OAuthCodeCacheKey holds what an authorization code was issued for, by hashed code
+	OAuthCodeCacheKey = "OAuthCode-%s"
+)
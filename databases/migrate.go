@@ -6,11 +6,20 @@ import (
 	"log"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
+// dataMigrations change rows in ways SQL can't, each runs right after the schema migration of its version
+var dataMigrations = []struct {
+	version uint
+	run     func(db *sql.DB) error
+}{
+	{version: 1752739200, run: normalizeUserPhones},
+}
+
 type Migrator struct {
 	DBURL string
 }
@@ -44,8 +53,8 @@ func (m *Migrator) Run(action string) {
 	// Execute the specified migration action
 	switch action {
 	case "up":
-		err = migrator.Up()
-		if err != nil && err != migrate.ErrNoChange {
+		err = up(migrator, driver, db)
+		if err != nil {
 			log.Fatal("Migration up failed:", err)
 		}
 		fmt.Println("Migrations applied successfully!")
@@ -61,3 +70,36 @@ func (m *Migrator) Run(action string) {
 		log.Fatal("Invalid migration action. Use 'up' or 'down'")
 	}
 }
+
+// up applies every migration, stopping at the version of each pending data migration to run it
+func up(migrator *migrate.Migrate, driver database.Driver, db *sql.DB) error {
+	for _, data := range dataMigrations {
+		version, _, err := migrator.Version()
+		if err != nil && err != migrate.ErrNilVersion {
+			return err
+		}
+		if err == nil && version >= data.version {
+			continue
+		}
+
+		err = migrator.Migrate(data.version)
+		if err != nil && err != migrate.ErrNoChange {
+			return err
+		}
+
+		if err := data.run(db); err != nil {
+			// Leave the version dirty like a failed SQL migration, so the data migration isn't skipped next time
+			if errDirty := driver.SetVersion(int(data.version), true); errDirty != nil {
+				log.Printf("Failed to mark migration %d dirty: %v", data.version, errDirty)
+			}
+			return fmt.Errorf("data migration %d: %w", data.version, err)
+		}
+	}
+
+	err := migrator.Up()
+	if err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public."refresh_token";
\ No newline at end of file
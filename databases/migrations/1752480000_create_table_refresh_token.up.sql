@@ -0,0 +1,16 @@
+CREATE TABLE public."refresh_token" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "family_id" VARCHAR(64) NOT NULL,  -- shared by every token rotated from the same login
+    "session_id" VARCHAR(64) NOT NULL DEFAULT '',
+    "token_hash" VARCHAR(64) NOT NULL UNIQUE,  -- sha256 of the opaque token, the token itself is never stored
+    "expires_at" INT NOT NULL,
+    "rotated_at" INT,  -- set once the token has been exchanged for a new one
+    "revoked_at" INT,
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE INDEX refresh_token_user_id_idx ON public."refresh_token"("user_id");
+CREATE INDEX refresh_token_family_id_idx ON public."refresh_token"("family_id");
@@ -0,0 +1,4 @@
+ALTER TABLE public."user" DROP COLUMN IF EXISTS "identity_provider";
+ALTER TABLE public."app" DROP COLUMN IF EXISTS "identity_provider";
+
+DROP TABLE IF EXISTS public."user_password";
\ No newline at end of file
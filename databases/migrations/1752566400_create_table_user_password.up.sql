@@ -0,0 +1,12 @@
+CREATE TABLE public."user_password" (
+    "id" SERIAL PRIMARY KEY,
+    "subject" VARCHAR(100) NOT NULL UNIQUE,  -- user.clerk_id of the local user, e.g. 'local_...'
+    "password_hash" VARCHAR(255) NOT NULL,
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+-- Which identity provider holds the credentials, 'clerk' or 'local'
+ALTER TABLE public."app" ADD COLUMN "identity_provider" VARCHAR(20) NOT NULL DEFAULT 'clerk';
+ALTER TABLE public."user" ADD COLUMN "identity_provider" VARCHAR(20) NOT NULL DEFAULT 'clerk';
@@ -0,0 +1 @@
+ALTER TABLE public."user" ADD COLUMN "is_phone_verified" BOOLEAN NOT NULL DEFAULT FALSE;
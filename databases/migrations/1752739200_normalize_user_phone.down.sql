@@ -0,0 +1,2 @@
+-- The normalized phones are kept, they are still valid phones
+DROP TABLE IF EXISTS public."phone_normalization_conflict";
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- Phones are kept in E.164 since they are normalized on write (internal/phone). The older rows are
+-- rewritten by the normalizeUserPhones data migration (databases/phone.go) right after this one, with
+-- the same parser and PHONE_DEFAULT_REGION. Rows that can't be parsed or whose normalized phone belongs
+-- to another user are left as they are and listed here to be fixed by hand. The table may exist when a
+-- failed data migration is forced back and run again.
+CREATE TABLE IF NOT EXISTS public."phone_normalization_conflict" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL,
+    "phone" VARCHAR(20) NOT NULL,
+    "normalized_phone" VARCHAR(20),
+    "conflicting_user_id" INT,  -- NULL when the phone couldn't be parsed
+    "created_at" INT NOT NULL
+);
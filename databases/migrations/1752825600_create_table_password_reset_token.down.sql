@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public."password_reset_token";
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE public."password_reset_token" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "token_hash" VARCHAR(64) NOT NULL UNIQUE,  -- sha256 of the emailed token, the token itself is never stored
+    "expires_at" INT NOT NULL,
+    "used_at" INT,  -- set once the token has reset the password, or when a newer reset revoked it
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE INDEX password_reset_token_user_id_idx ON public."password_reset_token"("user_id");
@@ -0,0 +1,12 @@
+CREATE TABLE public."security_event" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT REFERENCES public."user"("id") ON DELETE SET NULL,  -- empty for events about unknown accounts
+    "type" VARCHAR(50) NOT NULL,  -- e.g. 'password_reset'
+    "ip" VARCHAR(45) NOT NULL DEFAULT '',
+    "user_agent" TEXT NOT NULL DEFAULT '',
+    "metadata" JSONB NOT NULL DEFAULT '{}',
+    "created_at" INT NOT NULL
+);
+
+CREATE INDEX security_event_user_id_idx ON public."security_event"("user_id");
+CREATE INDEX security_event_type_idx ON public."security_event"("type");
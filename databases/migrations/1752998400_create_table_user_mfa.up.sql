@@ -0,0 +1,25 @@
+CREATE TABLE public."user_mfa" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "secret" TEXT NOT NULL,  -- TOTP secret, AES-GCM encrypted with MFA_ENCRYPTION_KEY
+    "confirmed_at" INT,  -- MFA is only enforced once the first code has been confirmed
+    "last_used_step" BIGINT NOT NULL DEFAULT 0,  -- TOTP time step of the last accepted code, older codes are replays
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+-- One authenticator per user, disabled ones are soft deleted
+CREATE UNIQUE INDEX user_mfa_user_id_idx ON public."user_mfa"("user_id") WHERE "deleted_at" IS NULL;
+
+CREATE TABLE public."mfa_recovery_code" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "code_hash" VARCHAR(64) NOT NULL,  -- sha256 of the code, the code itself is only shown once
+    "used_at" INT,
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE INDEX mfa_recovery_code_user_id_idx ON public."mfa_recovery_code"("user_id");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public."webauthn_credential";
\ No newline at end of file
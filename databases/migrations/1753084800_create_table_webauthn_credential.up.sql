@@ -0,0 +1,18 @@
+CREATE TABLE public."webauthn_credential" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "credential_id" VARCHAR(1400) NOT NULL,  -- base64url of the credential ID, authenticators make them up to 1023 bytes
+    "public_key" TEXT NOT NULL,  -- base64url of the COSE public key
+    "sign_count" BIGINT NOT NULL DEFAULT 0,  -- last signature counter seen, a counter that goes backwards means a cloned authenticator
+    "aaguid" VARCHAR(36) NOT NULL DEFAULT '',
+    "transports" VARCHAR(255) NOT NULL DEFAULT '',  -- comma separated hints for the browser, e.g. 'internal,hybrid'
+    "backup_eligible" BOOLEAN NOT NULL DEFAULT FALSE,  -- synced passkeys, e.g. iCloud Keychain or Google Password Manager
+    "name" VARCHAR(100) NOT NULL,
+    "last_used_at" INT,
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE UNIQUE INDEX webauthn_credential_credential_id_idx ON public."webauthn_credential"("credential_id") WHERE "deleted_at" IS NULL;
+CREATE INDEX webauthn_credential_user_id_idx ON public."webauthn_credential"("user_id");
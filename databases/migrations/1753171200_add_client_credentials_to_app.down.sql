@@ -0,0 +1,4 @@
+ALTER TABLE public."app" DROP COLUMN "previous_client_secret_expires_at";
+ALTER TABLE public."app" DROP COLUMN "previous_client_secret_hash";
+ALTER TABLE public."app" DROP COLUMN "client_secret_hash";
+ALTER TABLE public."app" DROP COLUMN "client_id";
\ No newline at end of file
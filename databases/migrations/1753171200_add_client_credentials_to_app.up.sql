@@ -0,0 +1,11 @@
+-- Client credentials of an app, only a sha256 hash of the secret is kept. After a rotation the previous secret keeps
+-- working until previous_client_secret_expires_at
+ALTER TABLE public."app" ADD COLUMN "client_id" VARCHAR(64);
+UPDATE public."app" SET "client_id" = md5(random()::text || "id"::text);
+ALTER TABLE public."app" ALTER COLUMN "client_id" SET NOT NULL;
+ALTER TABLE public."app" ADD CONSTRAINT "app_client_id_key" UNIQUE ("client_id");
+
+-- Apps created before this migration have no secret until it is rotated
+ALTER TABLE public."app" ADD COLUMN "client_secret_hash" VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE public."app" ADD COLUMN "previous_client_secret_hash" VARCHAR(64);
+ALTER TABLE public."app" ADD COLUMN "previous_client_secret_expires_at" INT;
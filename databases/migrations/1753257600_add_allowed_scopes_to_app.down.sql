@@ -0,0 +1 @@
+ALTER TABLE public."app" DROP COLUMN "allowed_scopes";
\ No newline at end of file
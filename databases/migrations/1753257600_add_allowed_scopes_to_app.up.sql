@@ -0,0 +1,2 @@
+-- Scopes the app may ask for in its OAuth tokens, e.g. '{users:read}'
+ALTER TABLE public."app" ADD COLUMN "allowed_scopes" TEXT[] NOT NULL DEFAULT '{}';
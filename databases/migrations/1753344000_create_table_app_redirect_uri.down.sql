@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public."app_redirect_uri";
\ No newline at end of file
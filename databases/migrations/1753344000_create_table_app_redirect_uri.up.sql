@@ -0,0 +1,10 @@
+CREATE TABLE public."app_redirect_uri" (
+    "id" SERIAL PRIMARY KEY,
+    "app_id" INT NOT NULL REFERENCES public."app"("id") ON DELETE CASCADE,
+    "redirect_uri" VARCHAR(2000) NOT NULL,  -- compared exactly, no prefix or wildcard matching
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE UNIQUE INDEX app_redirect_uri_app_id_redirect_uri_idx ON public."app_redirect_uri"("app_id", "redirect_uri") WHERE "deleted_at" IS NULL;
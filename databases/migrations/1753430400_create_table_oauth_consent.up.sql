@@ -0,0 +1,11 @@
+CREATE TABLE public."oauth_consent" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES public."user"("id") ON DELETE CASCADE,
+    "app_id" INT NOT NULL REFERENCES public."app"("id") ON DELETE CASCADE,
+    "scopes" TEXT[] NOT NULL DEFAULT '{}',  -- every scope the user granted the app so far
+    "created_at" INT NOT NULL,
+    "updated_at" INT NOT NULL,
+    "deleted_at" INT
+);
+
+CREATE UNIQUE INDEX oauth_consent_user_id_app_id_idx ON public."oauth_consent"("user_id", "app_id") WHERE "deleted_at" IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public.user_lower_email_idx;
\ No newline at end of file
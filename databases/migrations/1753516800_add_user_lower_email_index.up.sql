@@ -0,0 +1,2 @@
+-- Logins look users up by their lowercased email
+CREATE INDEX user_lower_email_idx ON public."user"(lower("email"));
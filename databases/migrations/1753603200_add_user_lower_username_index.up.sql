@@ -0,0 +1,2 @@
+-- Local logins look users up by their lowercased username
+CREATE INDEX user_lower_username_idx ON public."user"(lower("username"));
@@ -0,0 +1 @@
+ALTER TABLE public."user" DROP COLUMN IF EXISTS "clerk_updated_at";
\ No newline at end of file
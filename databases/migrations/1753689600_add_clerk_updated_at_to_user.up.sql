@@ -0,0 +1,2 @@
+-- updated_at in milliseconds of the Clerk user the row was last synced from, older webhooks are ignored
+ALTER TABLE public."user" ADD COLUMN "clerk_updated_at" BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,87 @@
+package databases
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/phone"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// normalizeUserPhones rewrites the phones of older rows to E.164 with the parser and PHONE_DEFAULT_REGION
+// the service normalizes new phones with. Rows that can't be parsed or whose normalized phone belongs to
+// another user are left as they are and listed in phone_normalization_conflict.
+func normalizeUserPhones(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT "id", "phone" FROM public."user" WHERE "phone" <> '' ORDER BY "id"`)
+	if err != nil {
+		return err
+	}
+
+	type userPhone struct {
+		id    int
+		phone string
+	}
+	var users []userPhone
+	for rows.Next() {
+		var user userPhone
+		if err := rows.Scan(&user.id, &user.phone); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := utils.Now()
+	for _, user := range users {
+		normalized, errPhone := phone.Normalize(user.phone, config.AppConfig.PhoneDefaultRegion)
+		if errPhone == phone.ErrUnknownRegion {
+			// A misconfigured region would list every national number as a conflict
+			return errPhone
+		}
+		if errPhone != nil {
+			_, err := tx.Exec(`INSERT INTO public."phone_normalization_conflict" ("user_id", "phone", "created_at") VALUES ($1, $2, $3)`,
+				user.id, user.phone, now)
+			if err != nil {
+				return err
+			}
+			log.Printf("[Migration] user %d: phone %q is not a valid phone number", user.id, user.phone)
+			continue
+		}
+		if normalized == user.phone {
+			continue
+		}
+
+		var conflictingID int
+		err := tx.QueryRow(`SELECT "id" FROM public."user" WHERE "phone" = $1 AND "id" <> $2 LIMIT 1`, normalized, user.id).Scan(&conflictingID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			_, err := tx.Exec(`INSERT INTO public."phone_normalization_conflict" ("user_id", "phone", "normalized_phone", "conflicting_user_id", "created_at") VALUES ($1, $2, $3, $4, $5)`,
+				user.id, user.phone, normalized, conflictingID, now)
+			if err != nil {
+				return err
+			}
+			log.Printf("[Migration] user %d: phone %q normalizes to %q, which user %d already has", user.id, user.phone, normalized, conflictingID)
+			continue
+		}
+
+		_, err = tx.Exec(`UPDATE public."user" SET "phone" = $1, "updated_at" = $2 WHERE "id" = $3`, normalized, now, user.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
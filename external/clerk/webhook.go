@@ -0,0 +1,72 @@
+package clerk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookTolerance is how far the svix-timestamp may be from now, older deliveries are treated as replays
+const WebhookTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookMissingHeaders = errors.New("missing svix headers")
+	ErrWebhookTimestamp      = errors.New("svix timestamp is outside the tolerance")
+	ErrWebhookSignature      = errors.New("no matching svix signature")
+	ErrWebhookSecret         = errors.New("webhook secret is missing or invalid")
+)
+
+// VerifyWebhook verifies the Svix signature of a Clerk webhook delivery.
+// The secret is the "whsec_" prefixed signing secret of the endpoint, the signed
+// content is "<svix-id>.<svix-timestamp>.<body>" and svix-signature holds a space
+// separated list of "v1,<base64 HMAC-SHA256>" entries, one per active secret.
+// Without a usable secret every delivery is rejected, an empty key would let anyone sign.
+func VerifyWebhook(secret string, header http.Header, body []byte, now time.Time) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil || len(key) == 0 {
+		return ErrWebhookSecret
+	}
+
+	msgID := header.Get("svix-id")
+	msgTimestamp := header.Get("svix-timestamp")
+	msgSignature := header.Get("svix-signature")
+	if msgID == "" || msgTimestamp == "" || msgSignature == "" {
+		return ErrWebhookMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(msgTimestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookTimestamp
+	}
+	sentAt := time.Unix(timestamp, 0)
+	if now.Sub(sentAt) > WebhookTolerance || sentAt.Sub(now) > WebhookTolerance {
+		return ErrWebhookTimestamp
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msgID + "." + msgTimestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, versioned := range strings.Split(msgSignature, " ") {
+		version, signature, found := strings.Cut(versioned, ",")
+		if !found || version != "v1" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrWebhookSignature
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileSender writes emails as .eml files into an outbox directory instead of sending them, for development
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// Send writes the message into the outbox
+func (s *FileSender) Send(ctx context.Context, message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", string(filepath.Separator), "_").Replace(message.To)
+	path := filepath.Join(s.dir, fmt.Sprintf("%d-%s.eml", now.UnixNano(), recipient))
+	if err := os.WriteFile(path, format(s.from, message, now), 0o600); err != nil {
+		return err
+	}
+
+	log.Printf("Mail to %s written to %s", message.To, path)
+	return nil
+}
+
+// NewFileSender creates a new outbox mail sender
+func NewFileSender(dir string, from string) *FileSender {
+	return &FileSender{
+		dir:  dir,
+		from: from,
+	}
+}
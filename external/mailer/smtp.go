@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// Send sends the message, net/smtp has no context so ctx is only checked before dialing
+func (s *SMTPSender) Send(ctx context.Context, message *Message) error {
+	if err := validHeader(message.To, message.Subject); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	return smtp.SendMail(addr, auth, s.from, []string{message.To}, format(s.from, message, time.Now()))
+}
+
+// NewSMTPSender creates a new SMTP mail sender, the username may be empty for servers without auth
+func NewSMTPSender(host string, port int, username string, password string, from string) *SMTPSender {
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
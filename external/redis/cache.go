@@ -14,6 +14,21 @@ func SetCache(ctx context.Context, key string, value interface{}, expiration tim
 	return RedisClient.Set(ctx, key, value, expiration).Err()
 }
 
+// SetCacheIfNotExists sets a value only when the key is missing, it reports whether the value was set
+func SetCacheIfNotExists(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return RedisClient.SetNX(ctx, key, value, expiration).Result()
+}
+
+// SetCacheIfExists replaces a value only while the key still exists, it reports whether the value was set
+func SetCacheIfExists(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return RedisClient.SetXX(ctx, key, value, expiration).Result()
+}
+
+// ExpireCache sets a new expiration on a key, a missing key stays missing
+func ExpireCache(ctx context.Context, key string, expiration time.Duration) error {
+	return RedisClient.Expire(ctx, key, expiration).Err()
+}
+
 // GetCache retrieves a value from Redis
 func GetCache(ctx context.Context, key string) (string, error) {
 	val, err := RedisClient.Get(ctx, key).Result()
@@ -57,6 +72,55 @@ func DeleteCache(ctx context.Context, key string) error {
 	return RedisClient.Del(ctx, key).Err()
 }
 
+// TakeCache retrieves a value and deletes it, of concurrent callers only one gets the value
+func TakeCache(ctx context.Context, key string) (string, error) {
+	val, err := GetCache(ctx, key)
+	if err != nil || val == "" {
+		return "", err
+	}
+
+	deleted, err := RedisClient.Del(ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+	if deleted == 0 {
+		return "", nil
+	}
+	return val, nil
+}
+
+// Increment increments a counter and returns its new value, the expiration is set when the counter is created
+func Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	count, err := RedisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := RedisClient.Expire(ctx, key, expiration).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// AddToSet adds members to a set and refreshes the expiration of the whole set
+func AddToSet(ctx context.Context, key string, expiration time.Duration, members ...interface{}) error {
+	if err := RedisClient.SAdd(ctx, key, members...).Err(); err != nil {
+		return err
+	}
+	return RedisClient.Expire(ctx, key, expiration).Err()
+}
+
+// GetSetMembers retrieves every member of a set, a missing set is empty
+func GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	return RedisClient.SMembers(ctx, key).Result()
+}
+
+// RemoveFromSet removes members from a set
+func RemoveFromSet(ctx context.Context, key string, members ...interface{}) error {
+	return RedisClient.SRem(ctx, key, members...).Err()
+}
+
 // DeleteCacheByPrefix deletes all keys that match a given prefix
 func DeleteCacheByPrefix(ctx context.Context, prefix string) error {
 	iter := RedisClient.Scan(ctx, 0, fmt.Sprintf("%s*", prefix), 0).Iterator()
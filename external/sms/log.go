@@ -0,0 +1,20 @@
+package sms
+
+import (
+	"context"
+	"log"
+)
+
+// LogSender writes text messages to the log instead of sending them, for local use
+type LogSender struct{}
+
+// Send logs the message
+func (s *LogSender) Send(ctx context.Context, phone string, message string) error {
+	log.Printf("[SMS] to %s: %s", phone, message)
+	return nil
+}
+
+// NewLogSender creates a new log-only SMS sender
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
@@ -0,0 +1,13 @@
+package sms
+
+import "context"
+
+// SMS sender names, as configured in SMS_SENDER
+const (
+	SenderLog = "log"
+)
+
+// Sender sends text messages to phone numbers
+type Sender interface {
+	Send(ctx context.Context, phone string, message string) error
+}
@@ -40,6 +40,9 @@ const (
 	// KeyClientID represents the Current Client in http server context
 	KeyClientID contextKey = "ClientID"
 
+	// KeyScope represents the scopes of a token the current user granted to an app
+	KeyScope contextKey = "Scope"
+
 	// KeyIsSales represents the current type of customer
 	KeyIsSales contextKey = "IsSales"
 
@@ -161,6 +164,17 @@ func ClientID(ctx context.Context) *int {
 	return nil
 }
 
+// Scope gets the space separated scopes the current user granted to the app of the token,
+// nil when the user called with a token of its own
+func Scope(ctx context.Context) *string {
+	scope := ctx.Value(KeyScope)
+	if scope != nil {
+		v := scope.(string)
+		return &v
+	}
+	return nil
+}
+
 // IsSales gets current type of customer
 func IsSales(ctx context.Context) bool {
 	isSales := ctx.Value(KeyIsSales)
@@ -28,6 +28,12 @@ func NewContext(ctx context.Context, q Queryer) context.Context {
 	return ctx
 }
 
+// WithoutTransaction returns a context whose queries run outside the transaction of ctx,
+// for writes that have to outlive a rollback
+func WithoutTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txKey, nil)
+}
+
 // TxFromContext returns the trasanction object from the context
 func TxFromContext(ctx context.Context) (Queryer, bool) {
 	q, ok := ctx.Value(txKey).(Queryer)
@@ -0,0 +1,24 @@
+package datatransfers
+
+import "github.com/riskibarqy/bq-account-service/internal/models"
+
+// AppParams represent the http request data for creating or updating an app
+type AppParams struct {
+	Name             string `json:"name" validate:"required,max=100"`
+	Slug             string `json:"slug" validate:"required"`
+	IdentityProvider string `json:"identityProvider"`
+
+	// AllowedScopes replaces the scopes of the app, leaving it out keeps the current ones
+	AllowedScopes []string `json:"allowedScopes"`
+}
+
+// AppRedirectURIParams represent the http request data for registering a redirect URI of an app
+type AppRedirectURIParams struct {
+	RedirectURI string `json:"redirectUri" validate:"required,max=2000"`
+}
+
+// AppCredentials is an app together with its plain client secret, returned only when the secret is issued
+type AppCredentials struct {
+	*models.App
+	ClientSecret string `json:"clientSecret"`
+}
@@ -0,0 +1,30 @@
+package datatransfers
+
+// MFAEnrollment represents a pending TOTP enrollment, the QR code is a PNG data URI of the otpauth URI
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+	QRCode     string `json:"qrCode"`
+}
+
+// MFACodeParams represent the http request data for confirming a TOTP enrollment
+type MFACodeParams struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAReauthParams represent the http request data of MFA changes that need the password again
+type MFAReauthParams struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// MFALoginParams represent the http request data for the second step of a login, the code is
+// either a TOTP code or a recovery code
+type MFALoginParams struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// RecoveryCodesResponse represents freshly generated recovery codes, they are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,80 @@
+package datatransfers
+
+// OAuthTokenParams represent the form of a token request as RFC 6749 names its fields. The client
+// credentials come either from the form or from Basic authentication.
+type OAuthTokenParams struct {
+	GrantType    string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+
+	// Code, RedirectURI and CodeVerifier belong to the authorization_code grant
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// OAuthTokenResponse represents the token response of RFC 6749, its fields are snake case as the RFC names them
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+
+	// IDToken is issued when the openid scope was granted
+	IDToken string `json:"id_token,omitempty"`
+}
+
+// OAuthAuthorizeParams represent an authorization request, the query of /oauth/authorize the consent page
+// passes on, together with what the user decided
+type OAuthAuthorizeParams struct {
+	ResponseType        string `json:"responseType"`
+	ClientID            string `json:"clientId"`
+	RedirectURI         string `json:"redirectUri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"codeChallenge"`
+	CodeChallengeMethod string `json:"codeChallengeMethod"`
+	Nonce               string `json:"nonce"`
+
+	// Consent grants the scopes the user hasn't granted the app yet, Deny turns the request down
+	Consent bool `json:"consent"`
+	Deny    bool `json:"deny"`
+}
+
+// OAuthAuthorizeResponse either asks the user to consent to the scopes or tells where to send the browser
+type OAuthAuthorizeResponse struct {
+	ConsentRequired bool     `json:"consentRequired,omitempty"`
+	AppName         string   `json:"appName,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+	RedirectURI     string   `json:"redirectUri,omitempty"`
+}
+
+// UserInfo represents the standard claims of OpenID Connect about a user, snake case as the specification
+// names them. Only the claims of the granted scopes are set.
+type UserInfo struct {
+	Subject             string `json:"sub"`
+	Name                string `json:"name,omitempty"`
+	PreferredUsername   string `json:"preferred_username,omitempty"`
+	Email               string `json:"email,omitempty"`
+	EmailVerified       *bool  `json:"email_verified,omitempty"`
+	PhoneNumber         string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool  `json:"phone_number_verified,omitempty"`
+}
+
+// OpenIDConfiguration represents the discovery document of OpenID Connect
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
@@ -0,0 +1,15 @@
+package datatransfers
+
+import "github.com/riskibarqy/bq-account-service/internal/webauthn"
+
+// PasskeyRegisterParams represent the http request data for registering a passkey, the credential
+// is what navigator.credentials.create() resolved to
+type PasskeyRegisterParams struct {
+	Name       string                        `json:"name" validate:"required,max=100"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// PasskeyRenameParams represent the http request data for renaming a passkey
+type PasskeyRenameParams struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
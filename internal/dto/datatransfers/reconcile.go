@@ -0,0 +1,29 @@
+package datatransfers
+
+// ReconcileReport represents the drift found between Clerk and the user table
+type ReconcileReport struct {
+	DryRun     bool              `json:"dryRun"`
+	StartedAt  int               `json:"startedAt"`
+	FinishedAt int               `json:"finishedAt"`
+	ClerkUsers int               `json:"clerkUsers"`
+	LocalUsers int               `json:"localUsers"`
+	Missing    []*ReconcileEntry `json:"missing"`
+	Orphaned   []*ReconcileEntry `json:"orphaned"`
+	Mismatched []*ReconcileEntry `json:"mismatched"`
+	Applied    int               `json:"applied"`
+	Errors     []string          `json:"errors"`
+}
+
+// ReconcileEntry represents a single drifted user
+type ReconcileEntry struct {
+	ClerkID string                      `json:"clerkId"`
+	UserID  int                         `json:"userId,omitempty"`
+	Email   string                      `json:"email,omitempty"`
+	Changes map[string]*ReconcileChange `json:"changes,omitempty"`
+}
+
+// ReconcileChange represents a field that differs between Clerk and the user table
+type ReconcileChange struct {
+	Local string `json:"local"`
+	Clerk string `json:"clerk"`
+}
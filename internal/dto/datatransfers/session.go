@@ -0,0 +1,8 @@
+package datatransfers
+
+// ClientInfo describes the client a session is created from
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
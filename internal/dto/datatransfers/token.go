@@ -0,0 +1,14 @@
+package datatransfers
+
+// TokenResponse represents an issued access token and its refresh token
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshTokenParams represent the http request data for refreshing or revoking a token
+type RefreshTokenParams struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
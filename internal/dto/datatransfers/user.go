@@ -4,20 +4,27 @@ import "github.com/riskibarqy/bq-account-service/internal/models"
 
 // LoginParams represent the http request data for login user
 type LoginParams struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 // LoginResponse represents the response of login function
 type LoginResponse struct {
 	SessionID string       `json:"sessionId"`
 	User      *models.User `json:"user"`
+
+	Token *TokenResponse `json:"token"`
+
+	// MFARequired is set in place of a session when the user has MFA enabled, the MFA token
+	// is exchanged for the session at POST /login/mfa together with a code
+	MFARequired bool   `json:"mfaRequired,omitempty"`
+	MFAToken    string `json:"mfaToken,omitempty"`
 }
 
 // ChangePasswordParams represent the http request data for change password
 type ChangePasswordParams struct {
-	OldPassword string `json:"oldPassword"`
-	NewPassword string `json:"newPassword"`
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
 }
 
 type RegisterUser struct {
@@ -25,6 +32,33 @@ type RegisterUser struct {
 	Email    string `json:"email" validate:"required,email"`
 	Username string `json:"username"`
 	Phone    string `json:"phone"`
-	Password string `json:"password"`
+	Password string `json:"password" validate:"required"`
 	AppID    int    `json:"appId"`
 }
+
+// ForgotPasswordParams represent the http request data for requesting a password reset email
+type ForgotPasswordParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordParams represent the http request data for resetting a password with the emailed token
+type ResetPasswordParams struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// EmailLoginParams represent the http request data for requesting a login link and code by email
+type EmailLoginParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// EmailLoginVerifyParams represent the http request data for logging in with the emailed link token or code
+type EmailLoginVerifyParams struct {
+	Token string `json:"token" validate:"required_without=Code"`
+	Code  string `json:"code" validate:"required_without=Token,omitempty,numeric,len=6"`
+}
+
+// VerifyPhoneParams represent the http request data for verifying a phone with the texted code
+type VerifyPhoneParams struct {
+	Code string `json:"code" validate:"required,numeric"`
+}
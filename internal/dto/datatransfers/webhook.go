@@ -0,0 +1,11 @@
+package datatransfers
+
+import "encoding/json"
+
+// ClerkWebhookEvent represents the envelope of a Clerk webhook delivery
+type ClerkWebhookEvent struct {
+	Type      string          `json:"type"`
+	Object    string          `json:"object"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+}
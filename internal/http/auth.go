@@ -1,15 +1,68 @@
 package http
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
 	"github.com/riskibarqy/bq-account-service/internal/http/response"
 	"github.com/riskibarqy/bq-account-service/internal/types"
 	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
 )
 
-func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next http.Handler) http.Handler {
+// authOptions tune what authorizedOnly demands of the user on top of a valid token
+type authOptions struct {
+	requireVerified bool
+	requireAdmin    bool
+
+	// clientScope lets apps in with an OAuth token carrying the scope, users pass as usual
+	clientScope string
+
+	// usersOnly keeps the tokens apps got for themselves out, even with the scope
+	usersOnly bool
+}
+
+type authOption func(*authOptions)
+
+// requireAdmin rejects users that aren't listed in ADMIN_USER_IDS
+func requireAdmin() authOption {
+	return func(o *authOptions) {
+		o.requireAdmin = true
+	}
+}
+
+// allowClients also accepts access tokens issued to apps, as long as they carry the scope
+func allowClients(scope string) authOption {
+	return func(o *authOptions) {
+		o.clientScope = scope
+	}
+}
+
+// usersOnly rejects access tokens issued to apps, tokens users granted to apps pass with allowClients
+func usersOnly() authOption {
+	return func(o *authOptions) {
+		o.usersOnly = true
+	}
+}
+
+// requireVerified rejects users whose email isn't verified yet
+func requireVerified() authOption {
+	return func(o *authOptions) {
+		o.requireVerified = true
+	}
+}
+
+func (hs *Server) authorizedOnly(userService user.ServiceInterface, opts ...authOption) func(next http.Handler) http.Handler {
+	options := &authOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			var token string
@@ -26,6 +79,83 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 				return
 			}
 
+			if options.clientScope != "" && !options.usersOnly {
+				if clientClaims, errClient := config.ParseClientToken(token); errClient == nil {
+					hs.serveClient(w, r, next, token, clientClaims, options.clientScope)
+					return
+				}
+			}
+
+			claims, errToken := config.ParseJWTToken(token)
+			if errToken != nil {
+				tokenError(ctx, w, errToken)
+				return
+			}
+
+			// Revoked sessions are deleted from Redis, so their tokens stop working right away
+			currentSession, err := hs.sessionService.GetSession(ctx, claims.SessionID)
+			if err != nil && err.Error != types.ErrSessionNotFound {
+				err.Path = ".Server->authorizeOnly()" + err.Path
+				response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+				return
+			}
+			if err != nil || currentSession.UserID != claims.ID {
+				tokenError(ctx, w, types.ErrTokenRevoked)
+				return
+			}
+
+			currentUser, err := userService.GetUser(ctx, claims.ID)
+			if err != nil && err.Error != data.ErrNotFound {
+				err.Path = ".Server->authorizeOnly()" + err.Path
+				response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+				return
+			}
+
+			// A token for a user that has since been deleted or deactivated is no longer honoured
+			if err != nil || currentUser.DeletedAt != nil || !currentUser.IsActive {
+				tokenError(ctx, w, types.ErrTokenRevoked)
+				return
+			}
+
+			if options.requireVerified && !currentUser.IsVerified {
+				response.ErrorWithCode(ctx, w, "EmailNotVerified", types.ErrEmailNotVerified.Error(), http.StatusForbidden, types.Error{
+					Path:    ".Server->authorizeOnly()",
+					Message: types.ErrEmailNotVerified.Error(),
+					Error:   types.ErrEmailNotVerified,
+					Type:    types.ErrTypesHandlerError,
+				})
+				return
+			}
+
+			// Tokens a user granted to an app only work on the routes that take one of their scopes
+			if claims.ClientID != "" && !hs.clientAllowed(ctx, w, claims.AppID, claims.ClientID, claims.HasScope(options.clientScope), options.clientScope) {
+				return
+			}
+
+			if options.requireAdmin && !isAdmin(currentUser.ID) {
+				response.Error(ctx, w, "Forbidden", http.StatusForbidden, types.Error{
+					Path:    ".Server->authorizeOnly()",
+					Message: types.ErrForbidden.Error(),
+					Error:   types.ErrForbidden,
+					Type:    types.ErrTypesHandlerError,
+				})
+				return
+			}
+
+			go func() {
+				if err := hs.sessionService.TouchSession(context.Background(), currentSession); err != nil {
+					log.Printf("Failed to touch session: %v", err.Error)
+				}
+			}()
+
+			ctx = context.WithValue(ctx, appcontext.KeyUserID, currentUser.ID)
+			ctx = context.WithValue(ctx, appcontext.KeySessionID, claims.SessionID)
+			if claims.ClientID != "" {
+				ctx = context.WithValue(ctx, appcontext.KeyClientID, claims.AppID)
+				ctx = context.WithValue(ctx, appcontext.KeyScope, claims.Scope)
+			}
+			ctx = context.WithValue(ctx, appcontext.KeyLoginToken, token)
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 
@@ -33,6 +163,84 @@ func (hs *Server) authorizedOnly(userService user.ServiceInterface) func(next ht
 	}
 }
 
+// serveClient lets an app through with its ID in appcontext.KeyClientID
+func (hs *Server) serveClient(w http.ResponseWriter, r *http.Request, next http.Handler, token string, claims *config.ClientClaims, scope string) {
+	ctx := r.Context()
+
+	if !hs.clientAllowed(ctx, w, claims.AppID, claims.ClientID, claims.HasScope(scope), scope) {
+		return
+	}
+
+	ctx = context.WithValue(ctx, appcontext.KeyClientID, claims.AppID)
+	ctx = context.WithValue(ctx, appcontext.KeyLoginToken, token)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// clientAllowed writes the error response and returns false unless the token of an app may call the route. The
+// app must still exist and the scope must be both in the token and among the scopes the app is allowed now,
+// so taking a scope away applies right away. Routes without a scope take no app tokens at all.
+func (hs *Server) clientAllowed(ctx context.Context, w http.ResponseWriter, appID int, clientID string, tokenHasScope bool, scope string) bool {
+	client, err := hs.appService.GetApp(ctx, appID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".Server->clientAllowed()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return false
+	}
+	if err != nil || client.ClientID != clientID {
+		tokenError(ctx, w, types.ErrTokenRevoked)
+		return false
+	}
+
+	allowed := false
+	for _, s := range client.AllowedScopes {
+		if s == scope {
+			allowed = true
+		}
+	}
+	if scope == "" || !allowed || !tokenHasScope {
+		response.ErrorWithCode(ctx, w, "InsufficientScope", types.ErrInsufficientScope.Error(), http.StatusForbidden, types.Error{
+			Path:    ".Server->clientAllowed()",
+			Message: types.ErrInsufficientScope.Error(),
+			Error:   types.ErrInsufficientScope,
+			Type:    types.ErrTypesHandlerError,
+		})
+		return false
+	}
+
+	return true
+}
+
+// tokenError writes the unauthorized response of a rejected token, each reason gets its own code
+func tokenError(ctx context.Context, w http.ResponseWriter, err error) {
+	code := "Unauthorized"
+	switch err {
+	case types.ErrTokenExpired:
+		code = "TokenExpired"
+	case types.ErrTokenMalformed:
+		code = "TokenMalformed"
+	case types.ErrTokenRevoked:
+		code = "TokenRevoked"
+	}
+
+	response.ErrorWithCode(ctx, w, code, err.Error(), http.StatusUnauthorized, types.Error{
+		Path:    ".Server->authorizeOnly()",
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesHandlerError,
+	})
+}
+
+// isAdmin tells whether the user is one of ADMIN_USER_IDS
+func isAdmin(userID int) bool {
+	for _, id := range strings.Split(config.AppConfig.AdminUserIDs, ",") {
+		if adminID, err := strconv.Atoi(strings.TrimSpace(id)); err == nil && adminID == userID {
+			return true
+		}
+	}
+	return false
+}
+
 func getBearerToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	splitToken := strings.Split(token, "Bearer")
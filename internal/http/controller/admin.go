@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/lockout"
+)
+
+// AdminController represents the admin controller
+type AdminController struct {
+	lockoutService lockout.ServiceInterface
+	dataManager    *data.Manager
+}
+
+// UnlockUser lifts the login lockout of a user before its cooldown ends
+func (a *AdminController) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	userID, errConversion := strconv.Atoi(chi.URLParam(r, "userId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AdminController->UnlockUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.lockoutService.Unlock(ctx, userID, appcontext.UserID(ctx), clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AdminController->UnlockUser()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "User Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(
+	lockoutService lockout.ServiceInterface,
+	dataManager *data.Manager,
+) *AdminController {
+	return &AdminController{
+		lockoutService: lockoutService,
+		dataManager:    dataManager,
+	}
+}
@@ -0,0 +1,400 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/app"
+)
+
+// AppController represents the app controller
+type AppController struct {
+	appService  app.AppServiceInterface
+	dataManager *data.Manager
+}
+
+// AppList app list and count
+type AppList struct {
+	Data  []*models.App `json:"data"`
+	Count int           `json:"count"`
+}
+
+// ListApps lists the apps that aren't deleted
+func (a *AppController) ListApps(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	queryValues := r.URL.Query()
+	var limit = 10
+	var errConversion error
+	if queryValues.Get("limit") != "" {
+		limit, errConversion = strconv.Atoi(queryValues.Get("limit"))
+		if errConversion != nil {
+			err = &types.Error{
+				Path:    ".AppController->ListApps()",
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    types.ErrTypesHandlerError,
+			}
+			response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+	}
+
+	var page = 1
+	if queryValues.Get("page") != "" {
+		page, errConversion = strconv.Atoi(queryValues.Get("page"))
+		if errConversion != nil {
+			err = &types.Error{
+				Path:    ".AppController->ListApps()",
+				Message: errConversion.Error(),
+				Error:   errConversion,
+				Type:    types.ErrTypesHandlerError,
+			}
+			response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+	}
+
+	if limit < 0 {
+		limit = 10
+	}
+	if page < 0 {
+		page = 1
+	}
+	appList, count, err := a.appService.ListApps(ctx, &datatransfers.FindAllParams{
+		Limit: limit,
+		Page:  page,
+	})
+	if err != nil {
+		err.Path = ".AppController->ListApps()" + err.Path
+		if err.Error != data.ErrNotFound {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+	}
+	if appList == nil {
+		appList = []*models.App{}
+	}
+
+	response.JSON(w, http.StatusOK, AppList{
+		Data:  appList,
+		Count: count,
+	})
+}
+
+// GetAppByID gets a single app
+func (a *AppController) GetAppByID(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->GetAppByID()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	singleApp, err := a.appService.GetApp(ctx, appID)
+	if err != nil {
+		err.Path = ".AppController->GetAppByID()" + err.Path
+		if err.Error == data.ErrNotFound {
+			response.Error(ctx, w, "App Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, singleApp)
+}
+
+// CreateApp creates an app
+func (a *AppController) CreateApp(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.AppParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AppController->CreateApp()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var credentials *datatransfers.AppCredentials
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		credentials, err = a.appService.CreateApp(ctx, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->CreateApp()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, credentials)
+}
+
+// UpdateApp updates an app
+func (a *AppController) UpdateApp(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->UpdateApp()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	params := &datatransfers.AppParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AppController->UpdateApp()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var singleApp *models.App
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		singleApp, err = a.appService.UpdateApp(ctx, appID, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->UpdateApp()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, singleApp)
+}
+
+// DeleteApp soft deletes an app
+func (a *AppController) DeleteApp(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->DeleteApp()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.appService.DeleteApp(ctx, appID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->DeleteApp()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// RotateClientSecret issues a new client secret for an app, the previous one keeps working for the grace period
+func (a *AppController) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->RotateClientSecret()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var credentials *datatransfers.AppCredentials
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		credentials, err = a.appService.RotateClientSecret(ctx, appID, appcontext.UserID(ctx), clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->RotateClientSecret()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, credentials)
+}
+
+// ListRedirectURIs lists the redirect URIs registered for an app
+func (a *AppController) ListRedirectURIs(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->ListRedirectURIs()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	redirectURIs, err := a.appService.ListRedirectURIs(ctx, appID)
+	if err != nil {
+		err.Path = ".AppController->ListRedirectURIs()" + err.Path
+		a.writeError(ctx, w, err.Error, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, redirectURIs)
+}
+
+// AddRedirectURI registers a redirect URI for an app
+func (a *AppController) AddRedirectURI(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->AddRedirectURI()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	params := &datatransfers.AppRedirectURIParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AppController->AddRedirectURI()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var redirectURI *models.AppRedirectURI
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		redirectURI, err = a.appService.AddRedirectURI(ctx, appID, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->AddRedirectURI()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, redirectURI)
+}
+
+// RemoveRedirectURI removes a redirect URI of an app
+func (a *AppController) RemoveRedirectURI(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->RemoveRedirectURI()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	redirectURIID, errConversion := strconv.Atoi(chi.URLParam(r, "redirectUriId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".AppController->RemoveRedirectURI()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.appService.RemoveRedirectURI(ctx, appID, redirectURIID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AppController->RemoveRedirectURI()" + err.Path
+		a.writeError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// writeError responds to a failed app change
+func (a *AppController) writeError(ctx context.Context, w http.ResponseWriter, errTransaction error, err *types.Error) {
+	if _, ok := errTransaction.(types.FieldViolations); ok {
+		response.Error(ctx, w, "Validation failed", http.StatusUnprocessableEntity, *err)
+		return
+	}
+	switch errTransaction {
+	case data.ErrNotFound:
+		response.Error(ctx, w, "App Not Found", http.StatusNotFound, *err)
+	case types.ErrSlugAlreadyExists, types.ErrRedirectURIAlreadyRegistered:
+		response.Error(ctx, w, errTransaction.Error(), http.StatusUnprocessableEntity, *err)
+	default:
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+	}
+}
+
+// NewAppController creates a new app controller
+func NewAppController(
+	appService app.AppServiceInterface,
+	dataManager *data.Manager,
+) *AppController {
+	return &AppController{
+		appService:  appService,
+		dataManager: dataManager,
+	}
+}
@@ -0,0 +1,368 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/auth"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
+	"github.com/riskibarqy/bq-account-service/utils"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// emailLoginNonceCookie binds email logins to the browser that asked for them
+const emailLoginNonceCookie = "email_login_nonce"
+
+// AuthController represents the auth controller
+type AuthController struct {
+	authService auth.ServiceInterface
+	dataManager *data.Manager
+}
+
+// Login authenticates a user by email and password and starts a new session
+func (a *AuthController) Login(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.LoginParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->Login()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *datatransfers.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.authService.Login(ctx, params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->Login()" + err.Path
+		if errTransaction == types.ErrInvalidCredentials {
+			response.Error(ctx, w, "Email / password is wrong", http.StatusUnauthorized, *err)
+		} else if errTransaction == types.ErrLoginThrottled {
+			response.Error(ctx, w, errTransaction.Error(), http.StatusTooManyRequests, *err)
+		} else if err.Type == types.ErrTypesClerkError {
+			response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// LoginMFA exchanges the MFA token from Login and a TOTP or recovery code for a new session
+func (a *AuthController) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.MFALoginParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->LoginMFA()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *datatransfers.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.authService.LoginMFA(ctx, params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->LoginMFA()" + err.Path
+		switch errTransaction {
+		case types.ErrMFATokenInvalid, types.ErrMFACodeInvalid, types.ErrMFANotEnabled:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnauthorized, *err)
+		case types.ErrOTPTooManyAttempts, types.ErrLoginThrottled:
+			response.Error(ctx, w, err.Error.Error(), http.StatusTooManyRequests, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// LoginPasskey exchanges the response to passkey login options for a new session
+func (a *AuthController) LoginPasskey(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &webauthn.AssertionResponse{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->LoginPasskey()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *datatransfers.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.authService.LoginPasskey(ctx, params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->LoginPasskey()" + err.Path
+		switch errTransaction {
+		case types.ErrPasskeyInvalid, types.ErrPasskeyChallengeExpired:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusUnauthorized, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// RequestEmailLogin mails a login link and code. Like ForgotPassword it always answers 202 before knowing
+// whether the email belongs to an account; the browser gets the nonce cookie the link and code are bound to.
+func (a *AuthController) RequestEmailLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params := &datatransfers.EmailLoginParams{}
+	if err := decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->RequestEmailLogin()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	// A browser asking again keeps its nonce, so an email sent before a throttled request still works
+	var nonce string
+	if cookie, errCookie := r.Cookie(emailLoginNonceCookie); errCookie == nil {
+		nonce = cookie.Value
+	}
+	if nonce == "" {
+		var errToken error
+		nonce, errToken = utils.GenerateRandomToken(32)
+		if errToken != nil {
+			err := &types.Error{
+				Path:    ".AuthController->RequestEmailLogin()",
+				Message: errToken.Error(),
+				Error:   errToken,
+				Type:    types.ErrTypesHandlerError,
+			}
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+			return
+		}
+	}
+	setEmailLoginNonce(w, nonce, config.AppConfig.EmailLoginTTL)
+
+	go func(ctx context.Context) {
+		if err := a.authService.RequestEmailLogin(ctx, params.Email, nonce); err != nil {
+			err.Path = ".AuthController->RequestEmailLogin()" + err.Path
+			err.Log(ctx, logger.Tracer)
+		}
+	}(context.WithoutCancel(ctx))
+
+	response.JSON(w, http.StatusAccepted, "")
+}
+
+// VerifyEmailLogin exchanges the token of the login link or the emailed code for a new session
+func (a *AuthController) VerifyEmailLogin(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.EmailLoginVerifyParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->VerifyEmailLogin()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var nonce string
+	if cookie, errCookie := r.Cookie(emailLoginNonceCookie); errCookie == nil {
+		nonce = cookie.Value
+	}
+
+	var result *datatransfers.LoginResponse
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.authService.VerifyEmailLogin(ctx, params, nonce, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->VerifyEmailLogin()" + err.Path
+		switch errTransaction {
+		case types.ErrEmailLoginInvalid:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusUnauthorized, *err)
+		case types.ErrOTPTooManyAttempts:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusTooManyRequests, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	setEmailLoginNonce(w, "", -1)
+	response.JSON(w, http.StatusOK, result)
+}
+
+// setEmailLoginNonce sets the email login nonce cookie for maxAge seconds, a negative maxAge deletes it
+func setEmailLoginNonce(w http.ResponseWriter, nonce string, maxAge int) {
+	cookie := &http.Cookie{
+		Name:     emailLoginNonceCookie,
+		Value:    nonce,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   config.AppConfig.AppMode != "development",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// ChangePassword replaces the password of the current user
+func (a *AuthController) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.ChangePasswordParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->ChangePassword()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.authService.ChangePassword(ctx, appcontext.UserID(ctx), params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->ChangePassword()" + err.Path
+		if _, ok := errTransaction.(types.FieldViolations); ok {
+			response.Error(ctx, w, "Validation failed", http.StatusUnprocessableEntity, *err)
+		} else if errTransaction == types.ErrWrongPassword {
+			response.Error(ctx, w, "Wrong old password", http.StatusBadRequest, *err)
+		} else if errTransaction == types.ErrLoginThrottled {
+			response.Error(ctx, w, errTransaction.Error(), http.StatusTooManyRequests, *err)
+		} else if err.Type == types.ErrTypesClerkError {
+			response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// ForgotPassword mails a password reset link. It always answers 202, before knowing whether the
+// email belongs to an account, so neither the status nor the response time gives accounts away.
+func (a *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params := &datatransfers.ForgotPasswordParams{}
+	if err := decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->ForgotPassword()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	go func(ctx context.Context) {
+		if err := a.authService.ForgotPassword(ctx, params.Email); err != nil {
+			err.Path = ".AuthController->ForgotPassword()" + err.Path
+			err.Log(ctx, logger.Tracer)
+		}
+	}(context.WithoutCancel(ctx))
+
+	response.JSON(w, http.StatusAccepted, "")
+}
+
+// ResetPassword sets a new password with the token from the password reset email
+func (a *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.ResetPasswordParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".AuthController->ResetPassword()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.authService.ResetPassword(ctx, params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".AuthController->ResetPassword()" + err.Path
+		if _, ok := errTransaction.(types.FieldViolations); ok {
+			response.Error(ctx, w, "Validation failed", http.StatusUnprocessableEntity, *err)
+		} else if errTransaction == types.ErrPasswordResetTokenInvalid {
+			response.ErrorWithCode(ctx, w, "TokenInvalid", "Password reset link is invalid or expired", http.StatusBadRequest, *err)
+		} else if err.Type == types.ErrTypesClerkError {
+			response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// decodeAndValidate decodes the JSON body into params, a pointer to a struct, and validates it
+func decodeAndValidate(r *http.Request, params interface{}) *types.Error {
+	errDecode := json.NewDecoder(r.Body).Decode(params)
+	if errDecode != nil {
+		return &types.Error{
+			Message: errDecode.Error(),
+			Error:   errDecode,
+			Type:    types.ErrTypesHandlerError,
+		}
+	}
+
+	errValidation := validator.New().Struct(params)
+	if errValidation != nil {
+		return &types.Error{
+			Message: errValidation.Error(),
+			Error:   errValidation,
+			Type:    types.ErrTypesHandlerError,
+		}
+	}
+
+	return nil
+}
+
+// NewAuthController creates a new auth controller
+func NewAuthController(
+	authService auth.ServiceInterface,
+	dataManager *data.Manager,
+) *AuthController {
+	return &AuthController{
+		authService: authService,
+		dataManager: dataManager,
+	}
+}
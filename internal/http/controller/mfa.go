@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/mfa"
+)
+
+// MFAController represents the MFA controller
+type MFAController struct {
+	mfaService  mfa.ServiceInterface
+	dataManager *data.Manager
+}
+
+// Enroll starts a TOTP enrollment for the current user
+func (a *MFAController) Enroll(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	var result *datatransfers.MFAEnrollment
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.mfaService.Enroll(ctx, appcontext.UserID(ctx))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".MFAController->Enroll()" + err.Path
+		if errTransaction == types.ErrMFAAlreadyEnabled {
+			response.Error(ctx, w, err.Error.Error(), http.StatusConflict, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Confirm enables MFA for the current user with a code from the enrolled authenticator
+func (a *MFAController) Confirm(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.MFACodeParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".MFAController->Confirm()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var recoveryCodes []string
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		recoveryCodes, err = a.mfaService.Confirm(ctx, appcontext.UserID(ctx), params.Code, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".MFAController->Confirm()" + err.Path
+		switch errTransaction {
+		case types.ErrMFACodeInvalid, types.ErrMFANotEnrolled:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnprocessableEntity, *err)
+		case types.ErrMFAAlreadyEnabled:
+			response.Error(ctx, w, err.Error.Error(), http.StatusConflict, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, &datatransfers.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// Disable turns MFA off for the current user, the password is asked again
+func (a *MFAController) Disable(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.MFAReauthParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".MFAController->Disable()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.mfaService.Disable(ctx, appcontext.UserID(ctx), params.Password, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".MFAController->Disable()" + err.Path
+		a.reauthError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user, the password is asked again
+func (a *MFAController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.MFAReauthParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".MFAController->RegenerateRecoveryCodes()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var recoveryCodes []string
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		recoveryCodes, err = a.mfaService.RegenerateRecoveryCodes(ctx, appcontext.UserID(ctx), params.Password, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".MFAController->RegenerateRecoveryCodes()" + err.Path
+		a.reauthError(ctx, w, errTransaction, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, &datatransfers.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// reauthError responds to a failed MFA change that needed the password again
+func (a *MFAController) reauthError(ctx context.Context, w http.ResponseWriter, errTransaction error, err *types.Error) {
+	switch {
+	case errTransaction == types.ErrWrongPassword:
+		response.Error(ctx, w, "Wrong password", http.StatusUnauthorized, *err)
+	case errTransaction == types.ErrMFANotEnabled:
+		response.Error(ctx, w, err.Error.Error(), http.StatusUnprocessableEntity, *err)
+	case errTransaction == types.ErrLoginThrottled:
+		response.Error(ctx, w, err.Error.Error(), http.StatusTooManyRequests, *err)
+	case err.Type == types.ErrTypesClerkError:
+		response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+	default:
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+	}
+}
+
+// NewMFAController creates a new MFA controller
+func NewMFAController(
+	mfaService mfa.ServiceInterface,
+	dataManager *data.Manager,
+) *MFAController {
+	return &MFAController{
+		mfaService:  mfaService,
+		dataManager: dataManager,
+	}
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/oauth"
+)
+
+// errOAuthRequest is a token request that is missing or repeats a parameter
+var errOAuthRequest = errors.New("invalid token request")
+
+// OAuthController represents the oauth controller
+type OAuthController struct {
+	oauthService oauth.ServiceInterface
+	dataManager  *data.Manager
+}
+
+// Token is the OAuth token endpoint. It takes a form encoded request as RFC 6749 describes it, with the
+// client authenticating either with Basic authentication or with client_id and client_secret in the form.
+func (a *OAuthController) Token(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if errParse := r.ParseForm(); errParse != nil {
+		response.OAuthError(ctx, w, "invalid_request", errParse.Error(), http.StatusBadRequest, types.Error{
+			Path:    ".OAuthController->Token()",
+			Message: errParse.Error(),
+			Error:   errParse,
+			Type:    types.ErrTypesHandlerError,
+		})
+		return
+	}
+
+	params := &datatransfers.OAuthTokenParams{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Scope:        r.PostForm.Get("scope"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+
+	// Basic credentials are form encoded before they are base64 encoded, RFC 6749 section 2.3.1
+	username, password, basic := r.BasicAuth()
+	if basic {
+		clientID, errID := url.QueryUnescape(username)
+		clientSecret, errSecret := url.QueryUnescape(password)
+		if errID != nil || errSecret != nil || params.ClientSecret != "" || (params.ClientID != "" && params.ClientID != clientID) {
+			oauthRequestError(ctx, w, "client must authenticate with one method")
+			return
+		}
+		params.ClientID = clientID
+		params.ClientSecret = clientSecret
+	}
+
+	if params.GrantType == "" {
+		oauthRequestError(ctx, w, "grant_type is required")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	result, err := a.oauthService.Token(ctx, params, clientInfo(r))
+	if err != nil {
+		err.Path = ".OAuthController->Token()" + err.Path
+		code, status := oauthErrorCode(err.Error)
+		if code == "invalid_client" && basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		response.OAuthError(ctx, w, code, oauthErrorDescription(err.Error, status), status, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Authorize is the OAuth authorize endpoint. A valid request is sent on to OAUTH_AUTHORIZE_URL with its query, where
+// the user logs in and consents; an invalid one goes back to the redirect URI with the error, unless the client or
+// the redirect URI itself is wrong, which is shown here since the browser can't be sent anywhere safe.
+func (a *OAuthController) Authorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	params := &datatransfers.OAuthAuthorizeParams{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Nonce:               query.Get("nonce"),
+	}
+
+	_, _, err := a.oauthService.ValidateAuthorization(ctx, params)
+	if err != nil {
+		err.Path = ".OAuthController->Authorize()" + err.Path
+		code, status := oauthErrorCode(err.Error)
+		if err.Error == types.ErrInvalidClient || err.Error == types.ErrInvalidRedirectURI || status == http.StatusInternalServerError {
+			response.OAuthError(ctx, w, code, oauthErrorDescription(err.Error, status), authorizeStatus(status), *err)
+			return
+		}
+
+		http.Redirect(w, r, oauth.BuildRedirect(params.RedirectURI, url.Values{
+			"error":             {code},
+			"error_description": {err.Error.Error()},
+			"state":             {params.State},
+		}), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, config.AppConfig.OAuthAuthorizeURL+"?"+r.URL.RawQuery, http.StatusFound)
+}
+
+// Approve answers the authorization request of the consent page for the current user, either asking for consent
+// to new scopes or returning the redirect URI to send the browser to
+func (a *OAuthController) Approve(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.OAuthAuthorizeParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".OAuthController->Approve()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *datatransfers.OAuthAuthorizeResponse
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.oauthService.Authorize(ctx, appcontext.UserID(ctx), *appcontext.SessionID(ctx), params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".OAuthController->Approve()" + err.Path
+		code, status := oauthErrorCode(errTransaction)
+		response.OAuthError(ctx, w, code, oauthErrorDescription(errTransaction, status), authorizeStatus(status), *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// ListConsents lists the apps the current user granted scopes to
+func (a *OAuthController) ListConsents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	consents, err := a.oauthService.ListConsents(ctx, appcontext.UserID(ctx))
+	if err != nil {
+		err.Path = ".OAuthController->ListConsents()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, consents)
+}
+
+// RevokeConsent forgets the scopes the current user granted an app
+func (a *OAuthController) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	appID, errConversion := strconv.Atoi(chi.URLParam(r, "appId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".OAuthController->RevokeConsent()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.oauthService.RevokeConsent(ctx, appcontext.UserID(ctx), appID, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".OAuthController->RevokeConsent()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "Consent Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// UserInfo returns the OpenID Connect claims about the current user the token was granted
+func (a *OAuthController) UserInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	info, err := a.oauthService.UserInfo(ctx, appcontext.UserID(ctx), appcontext.Scope(ctx))
+	if err != nil {
+		err.Path = ".OAuthController->UserInfo()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, info)
+}
+
+// oauthErrorCode maps an error of the oauth service to its RFC 6749 error code and status
+func oauthErrorCode(err error) (string, int) {
+	switch err {
+	case types.ErrInvalidClient:
+		return "invalid_client", http.StatusUnauthorized
+	case types.ErrInvalidGrant:
+		return "invalid_grant", http.StatusBadRequest
+	case types.ErrInvalidScope:
+		return "invalid_scope", http.StatusBadRequest
+	case types.ErrUnsupportedGrantType:
+		return "unsupported_grant_type", http.StatusBadRequest
+	case types.ErrUnsupportedResponseType:
+		return "unsupported_response_type", http.StatusBadRequest
+	case types.ErrInvalidRedirectURI, types.ErrPKCERequired:
+		return "invalid_request", http.StatusBadRequest
+	default:
+		return "server_error", http.StatusInternalServerError
+	}
+}
+
+// authorizeStatus is the status of an authorization error, an unknown client is a bad request there since
+// the user agent has no credentials to retry with
+func authorizeStatus(status int) int {
+	if status == http.StatusUnauthorized {
+		return http.StatusBadRequest
+	}
+	return status
+}
+
+// oauthErrorDescription describes the error to the client, server errors aren't described
+func oauthErrorDescription(err error, status int) string {
+	if status == http.StatusInternalServerError {
+		return ""
+	}
+	return err.Error()
+}
+
+// oauthRequestError responds to a malformed token request
+func oauthRequestError(ctx context.Context, w http.ResponseWriter, description string) {
+	response.OAuthError(ctx, w, "invalid_request", description, http.StatusBadRequest, types.Error{
+		Path:    ".OAuthController->Token()",
+		Message: description,
+		Error:   errOAuthRequest,
+		Type:    types.ErrTypesHandlerError,
+	})
+}
+
+// NewOAuthController creates a new oauth controller
+func NewOAuthController(
+	oauthService oauth.ServiceInterface,
+	dataManager *data.Manager,
+) *OAuthController {
+	return &OAuthController{
+		oauthService: oauthService,
+		dataManager:  dataManager,
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/passkey"
+)
+
+// PasskeyController represents the passkey controller
+type PasskeyController struct {
+	passkeyService passkey.ServiceInterface
+	dataManager    *data.Manager
+}
+
+// RegistrationOptions starts a passkey registration for the current user
+func (a *PasskeyController) RegistrationOptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := a.passkeyService.RegistrationOptions(ctx, appcontext.UserID(ctx))
+	if err != nil {
+		err.Path = ".PasskeyController->RegistrationOptions()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Register stores a passkey of the current user from the response to the registration options
+func (a *PasskeyController) Register(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.PasskeyRegisterParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".PasskeyController->Register()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *models.WebAuthnCredential
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.passkeyService.Register(ctx, appcontext.UserID(ctx), params, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".PasskeyController->Register()" + err.Path
+		switch errTransaction {
+		case types.ErrPasskeyInvalid, types.ErrPasskeyChallengeExpired:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusUnprocessableEntity, *err)
+		case types.ErrPasskeyAlreadyRegistered:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusConflict, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, result)
+}
+
+// ListPasskeys lists the passkeys of the current user
+func (a *PasskeyController) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := a.passkeyService.ListPasskeys(ctx, appcontext.UserID(ctx))
+	if err != nil {
+		err.Path = ".PasskeyController->ListPasskeys()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// RenamePasskey renames a passkey of the current user
+func (a *PasskeyController) RenamePasskey(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	passkeyID, errConversion := strconv.Atoi(chi.URLParam(r, "passkeyId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".PasskeyController->RenamePasskey()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	params := &datatransfers.PasskeyRenameParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".PasskeyController->RenamePasskey()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var result *models.WebAuthnCredential
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err = a.passkeyService.RenamePasskey(ctx, appcontext.UserID(ctx), passkeyID, params.Name)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".PasskeyController->RenamePasskey()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "Passkey Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// RemovePasskey removes a passkey of the current user
+func (a *PasskeyController) RemovePasskey(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	passkeyID, errConversion := strconv.Atoi(chi.URLParam(r, "passkeyId"))
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".PasskeyController->RemovePasskey()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.passkeyService.RemovePasskey(ctx, appcontext.UserID(ctx), passkeyID, clientInfo(r))
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".PasskeyController->RemovePasskey()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "Passkey Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// LoginOptions starts a passkey login, the response goes to AuthController.LoginPasskey
+func (a *PasskeyController) LoginOptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := a.passkeyService.LoginOptions(ctx)
+	if err != nil {
+		err.Path = ".PasskeyController->LoginOptions()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// NewPasskeyController creates a new passkey controller
+func NewPasskeyController(
+	passkeyService passkey.ServiceInterface,
+	dataManager *data.Manager,
+) *PasskeyController {
+	return &PasskeyController{
+		passkeyService: passkeyService,
+		dataManager:    dataManager,
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+)
+
+// SessionController represents the session controller
+type SessionController struct {
+	sessionService session.ServiceInterface
+}
+
+// ListSessions lists the active sessions of the current user
+func (a *SessionController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var currentSessionID string
+	if sessionID := appcontext.SessionID(ctx); sessionID != nil {
+		currentSessionID = *sessionID
+	}
+
+	sessions, err := a.sessionService.ListSessions(ctx, appcontext.UserID(ctx), currentSessionID)
+	if err != nil {
+		err.Path = ".SessionController->ListSessions()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession revokes one session of the current user
+func (a *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := a.sessionService.RevokeSession(ctx, appcontext.UserID(ctx), chi.URLParam(r, "sessionId"))
+	if err != nil {
+		err.Path = ".SessionController->RevokeSession()" + err.Path
+		if err.Error == types.ErrSessionNotFound {
+			response.Error(ctx, w, "Session Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// RevokeOtherSessions revokes every session of the current user except the one making the request
+func (a *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var currentSessionID string
+	if sessionID := appcontext.SessionID(ctx); sessionID != nil {
+		currentSessionID = *sessionID
+	}
+
+	err := a.sessionService.RevokeOtherSessions(ctx, appcontext.UserID(ctx), currentSessionID)
+	if err != nil {
+		err.Path = ".SessionController->RevokeOtherSessions()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// clientInfo describes the client of the request, the remote address has been
+// resolved from the trusted proxies already
+func clientInfo(r *http.Request) *datatransfers.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	userAgent := r.UserAgent()
+	device := r.Header.Get("X-Device-Name")
+	if device == "" {
+		device = deviceFromUserAgent(userAgent)
+	}
+
+	return &datatransfers.ClientInfo{
+		Device:    device,
+		UserAgent: userAgent,
+		IP:        ip,
+	}
+}
+
+// deviceFromUserAgent guesses a readable device name when the client didn't send one
+func deviceFromUserAgent(userAgent string) string {
+	devices := []struct{ token, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Macintosh", "Mac"},
+		{"CrOS", "Chromebook"},
+		{"Linux", "Linux"},
+	}
+	for _, device := range devices {
+		if strings.Contains(userAgent, device.token) {
+			return device.name
+		}
+	}
+	return "Unknown"
+}
+
+// NewSessionController creates a new session controller
+func NewSessionController(
+	sessionService session.ServiceInterface,
+) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/token"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// TokenController represents the token controller
+type TokenController struct {
+	tokenService token.ServiceInterface
+}
+
+// Refresh rotates the refresh token and issues a new access token
+func (a *TokenController) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, err := decodeRefreshTokenParams(r)
+	if err != nil {
+		err.Path = ".TokenController->Refresh()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	result, err := a.tokenService.RefreshTokens(ctx, params.RefreshToken)
+	if err != nil {
+		err.Path = ".TokenController->Refresh()" + err.Path
+		switch err.Error {
+		case types.ErrRefreshTokenInvalid, types.ErrRefreshTokenExpired, types.ErrRefreshTokenReused:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnauthorized, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// Revoke revokes the refresh token together with every token rotated from the same login
+func (a *TokenController) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, err := decodeRefreshTokenParams(r)
+	if err != nil {
+		err.Path = ".TokenController->Revoke()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	err = a.tokenService.RevokeRefreshToken(ctx, params.RefreshToken)
+	if err != nil {
+		err.Path = ".TokenController->Revoke()" + err.Path
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+func decodeRefreshTokenParams(r *http.Request) (*datatransfers.RefreshTokenParams, *types.Error) {
+	var params *datatransfers.RefreshTokenParams
+	errDecode := json.NewDecoder(r.Body).Decode(&params)
+	if errDecode != nil {
+		return nil, &types.Error{
+			Message: errDecode.Error(),
+			Error:   errDecode,
+			Type:    types.ErrTypesHandlerError,
+		}
+	}
+
+	errValidation := validator.New().Struct(params)
+	if errValidation != nil {
+		return nil, &types.Error{
+			Message: errValidation.Error(),
+			Error:   errValidation,
+			Type:    types.ErrTypesHandlerError,
+		}
+	}
+
+	return params, nil
+}
+
+// NewTokenController creates a new token controller
+func NewTokenController(
+	tokenService token.ServiceInterface,
+) *TokenController {
+	return &TokenController{
+		tokenService: tokenService,
+	}
+}
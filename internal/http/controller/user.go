@@ -6,19 +6,23 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/go-chi/chi"
+	"github.com/riskibarqy/bq-account-service/external/logger"
 	"github.com/riskibarqy/bq-account-service/internal/data"
 	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
 	"github.com/riskibarqy/bq-account-service/internal/http/response"
 	"github.com/riskibarqy/bq-account-service/internal/models"
 	"github.com/riskibarqy/bq-account-service/internal/types"
 	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/verification"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 // UserController represents the user controller
 type UserController struct {
-	userService user.ServiceInterface
-	dataManager *data.Manager
+	userService         user.ServiceInterface
+	verificationService verification.ServiceInterface
+	dataManager         *data.Manager
 }
 
 // UserList user list and count
@@ -27,133 +31,82 @@ type UserList struct {
 	Count int            `json:"count"`
 }
 
-// func (a *UserController) Login(w http.ResponseWriter, r *http.Request) {
-// 	var err *types.Error
-
-// 	decoder := json.NewDecoder(r.Body)
-
-// 	var params datatransfers.LoginParams
-// 	errDecode := decoder.Decode(&params)
-// 	if errDecode != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->Login()",
-// 			Message: errDecode.Error(),
-// 			Error:   errDecode,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
-
-// 	var sess *datatransfers.LoginResponse
-// 	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-// 		sess, err = a.userService.Login(r.Context(), params.Email, params.Password)
-// 		if err != nil {
-// 			return err.Error
-// 		}
-// 		return nil
-// 	})
-// 	if errTransaction != nil {
-// 		err.Path = ".UserController->Login()" + err.Path
-// 		if err.Error == user.ErrWrongPassword || err.Error == data.ErrNotFound {
-// 			response.Error(w, "Email / password is wrong", http.StatusBadRequest, *err)
-// 		} else {
-// 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-// 		}
-// 		return
-// 	}
-
-// 	http.SetCookie(w, &http.Cookie{
-// 		Name:  "sessionId",
-// 		Value: sess.SessionID,
-// 	})
-
-// 	response.JSON(w, http.StatusOK, sess)
-// }
-
-// func (a *UserController) ChangePassword(w http.ResponseWriter, r *http.Request) {
-// 	var err *types.Error
-
-// 	decoder := json.NewDecoder(r.Body)
-// 	var params datatransfers.ChangePasswordParams
-// 	errDecode := decoder.Decode(&params)
-// 	if errDecode != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->ChangePassword()",
-// 			Message: errDecode.Error(),
-// 			Error:   errDecode,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
-
-// 	userID := appcontext.UserID(r.Context())
-
-// 	err = a.userService.ChangePassword(r.Context(), userID, params.OldPassword, params.NewPassword)
-// 	if err != nil {
-// 		err.Path = ".UserController->ChangePassword()" + err.Path
-// 		if err.Error == user.ErrWrongPassword {
-// 			response.Error(w, "Wrong old password", http.StatusBadRequest, *err)
-// 		} else {
-// 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-// 		}
-// 		return
-// 	}
-
-// 	response.JSON(w, http.StatusNoContent, "")
-// }
+// UpdateUser updates the profile of a user
+func (a *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
 
-// func (a *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
-// 	var err *types.Error
+	decoder := json.NewDecoder(r.Body)
 
-// 	decoder := json.NewDecoder(r.Body)
+	var params *models.User
+	errDecode := decoder.Decode(&params)
+	if errDecode != nil {
+		err = &types.Error{
+			Path:    ".UserController->UpdateUser()",
+			Message: errDecode.Error(),
+			Error:   errDecode,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
 
-// 	var params *models.User
-// 	errDecode := decoder.Decode(&params)
-// 	if errDecode != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->UpdateUser()",
-// 			Message: errDecode.Error(),
-// 			Error:   errDecode,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
-// 	var sUserID = chi.URLParam(r, "userId")
-// 	userID, errConversion := strconv.Atoi(sUserID)
-// 	if errConversion != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->UpdateUser()",
-// 			Message: errConversion.Error(),
-// 			Error:   errConversion,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
+	validate := validator.New()
+	errValidation := validate.StructPartial(params, "Name", "Email")
+	if errValidation != nil {
+		err = &types.Error{
+			Path:    ".UserController->UpdateUser()",
+			Message: errValidation.Error(),
+			Error:   errValidation,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
 
-// 	var singleUser *models.User
-// 	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-// 		singleUser, err = a.userService.UpdateUser(ctx, userID, params)
-// 		if err != nil {
-// 			return err.Error
-// 		}
-// 		return nil
-// 	})
-// 	if errTransaction != nil {
-// 		err.Path = ".UserController->UpdateUser()" + err.Path
-// 		if errTransaction == user.ErrEmailAlreadyExists {
-// 			response.Error(w, "email has been registered", http.StatusUnprocessableEntity, *err)
-// 		} else {
-// 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-// 		}
-// 		return
-// 	}
-// 	response.JSON(w, http.StatusOK, singleUser)
+	var sUserID = chi.URLParam(r, "userId")
+	userID, errConversion := strconv.Atoi(sUserID)
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->UpdateUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
 
-// }
+	var singleUser *models.User
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		singleUser, err = a.userService.UpdateUser(ctx, userID, params)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->UpdateUser()" + err.Path
+		if _, ok := errTransaction.(types.FieldViolations); ok {
+			response.Error(ctx, w, "Validation failed", http.StatusUnprocessableEntity, *err)
+			return
+		}
+		switch errTransaction {
+		case data.ErrNotFound:
+			response.Error(ctx, w, "User Not Found", http.StatusNotFound, *err)
+		case types.ErrEmailAlreadyExists, types.ErrPhoneAlreadyExists, types.ErrUsernameAlreadyExists:
+			response.Error(ctx, w, errTransaction.Error(), http.StatusUnprocessableEntity, *err)
+		default:
+			if err.Type == types.ErrTypesClerkError {
+				response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+				return
+			}
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+	response.JSON(w, http.StatusOK, singleUser)
+}
 
 func (a *UserController) Register(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
@@ -197,47 +150,65 @@ func (a *UserController) Register(w http.ResponseWriter, r *http.Request) {
 	})
 	if errTransaction != nil {
 		err.Path = ".UserController->Register()" + err.Path
-		if errTransaction == types.ErrUserAlreadyExists {
+		if _, ok := errTransaction.(types.FieldViolations); ok {
+			response.Error(ctx, w, "Validation failed", http.StatusUnprocessableEntity, *err)
+		} else if errTransaction == types.ErrUserAlreadyExists {
 			response.Error(ctx, w, types.ErrUserAlreadyExists.Error(), http.StatusUnprocessableEntity, *err)
+		} else if errTransaction == types.ErrUnknownIdentityProvider || errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "Invalid App", http.StatusUnprocessableEntity, *err)
 		} else {
 			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
 		}
 		return
 	}
 
+	// The user is registered either way, a failed email can be sent again through the resend endpoint
+	if err = a.verificationService.SendEmailVerification(ctx, result); err != nil {
+		err.Path = ".UserController->Register()" + err.Path
+		err.Log(ctx, logger.Tracer)
+	}
+
 	response.JSON(w, http.StatusOK, result)
 }
 
-// func (a *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
-// 	var err *types.Error
-// 	var sUserID = chi.URLParam(r, "userId")
-// 	userID, errConversion := strconv.Atoi(sUserID)
-// 	if errConversion != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->DeleteUser()",
-// 			Message: errConversion.Error(),
-// 			Error:   errConversion,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
+// DeleteUser soft deletes a user
+func (a *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
 
-// 	errTransaction := a.dataManager.RunInTransaction(r.Context(), func(ctx context.Context) error {
-// 		err = a.userService.DeleteUser(ctx, userID)
-// 		if err != nil {
-// 			return err.Error
-// 		}
-// 		return nil
-// 	})
-// 	if errTransaction != nil {
-// 		err.Path = ".USerController->DeleteUser()" + err.Path
-// 		response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-// 		return
-// 	}
-// 	response.JSON(w, http.StatusNoContent, "")
+	var sUserID = chi.URLParam(r, "userId")
+	userID, errConversion := strconv.Atoi(sUserID)
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->DeleteUser()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
 
-// }
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = a.userService.DeleteUser(ctx, userID)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".UserController->DeleteUser()" + err.Path
+		if errTransaction == data.ErrNotFound {
+			response.Error(ctx, w, "User Not Found", http.StatusNotFound, *err)
+		} else if err.Type == types.ErrTypesClerkError {
+			response.Error(ctx, w, "Bad Gateway", http.StatusBadGateway, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+	response.JSON(w, http.StatusNoContent, "")
+}
 
 func (a *UserController) ListUser(w http.ResponseWriter, r *http.Request) {
 	var err *types.Error
@@ -302,44 +273,47 @@ func (a *UserController) ListUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// func (a *UserController) GetUserByID(w http.ResponseWriter, r *http.Request) {
-// 	var err *types.Error
-
-// 	var sUserID = chi.URLParam(r, "userId")
-// 	userID, errConversion := strconv.Atoi(sUserID)
-// 	if errConversion != nil {
-// 		err = &types.Error{
-// 			Path:    ".UserController->UpdateUser()",
-// 			Message: errConversion.Error(),
-// 			Error:   errConversion,
-// 			Type:    types.ErrTypesHandlerError,
-// 		}
-// 		response.Error(w, "Bad Request", http.StatusBadRequest, *err)
-// 		return
-// 	}
+// GetUserByID gets a single user
+func (a *UserController) GetUserByID(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
 
-// 	user, err := a.userService.GetUser(r.Context(), userID)
-// 	if err != nil {
-// 		err.Path = ".UserController->GetUserByID()" + err.Path
-// 		response.Error(w, "User Not Found", http.StatusNotFound, *err)
-// 		if err.Error != data.ErrNotFound {
-// 			response.Error(w, "Internal Server Error", http.StatusInternalServerError, *err)
-// 			return
-// 		}
-// 		return
-// 	}
+	var sUserID = chi.URLParam(r, "userId")
+	userID, errConversion := strconv.Atoi(sUserID)
+	if errConversion != nil {
+		err = &types.Error{
+			Path:    ".UserController->GetUserByID()",
+			Message: errConversion.Error(),
+			Error:   errConversion,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
 
-// 	response.JSON(w, http.StatusOK, user)
+	user, err := a.userService.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".UserController->GetUserByID()" + err.Path
+		if err.Error == data.ErrNotFound {
+			response.Error(ctx, w, "User Not Found", http.StatusNotFound, *err)
+		} else {
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
 
-// }
+	response.JSON(w, http.StatusOK, user)
+}
 
 // NewUserController creates a new user controller
 func NewUserController(
 	userService user.ServiceInterface,
+	verificationService verification.ServiceInterface,
 	dataManager *data.Manager,
 ) *UserController {
 	return &UserController{
-		userService: userService,
-		dataManager: dataManager,
+		userService:         userService,
+		verificationService: verificationService,
+		dataManager:         dataManager,
 	}
 }
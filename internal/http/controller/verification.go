@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/riskibarqy/bq-account-service/internal/appcontext"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/verification"
+)
+
+// VerificationController represents the verification controller
+type VerificationController struct {
+	verificationService verification.ServiceInterface
+	dataManager         *data.Manager
+}
+
+// VerifyEmail verifies the email of the user the token in the verification link was issued for
+func (a *VerificationController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		err = &types.Error{
+			Path:    ".VerificationController->VerifyEmail()",
+			Message: types.ErrTokenMalformed.Error(),
+			Error:   types.ErrTokenMalformed,
+			Type:    types.ErrTypesHandlerError,
+		}
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var verifiedUser *models.User
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		verifiedUser, err = a.verificationService.VerifyEmail(ctx, token)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".VerificationController->VerifyEmail()" + err.Path
+		switch errTransaction {
+		case types.ErrTokenExpired:
+			response.ErrorWithCode(ctx, w, "TokenExpired", "Verification link has expired", http.StatusBadRequest, *err)
+		case types.ErrTokenInvalid, data.ErrNotFound:
+			response.ErrorWithCode(ctx, w, "TokenInvalid", "Verification link is invalid", http.StatusBadRequest, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, verifiedUser)
+}
+
+// ResendEmailVerification sends the verification email of the current user again
+func (a *VerificationController) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := a.verificationService.ResendEmailVerification(ctx, appcontext.UserID(ctx))
+	if err != nil {
+		err.Path = ".VerificationController->ResendEmailVerification()" + err.Path
+		switch err.Error {
+		case types.ErrEmailAlreadyVerified:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnprocessableEntity, *err)
+		case types.ErrTooManyRequests:
+			response.Error(ctx, w, err.Error.Error(), http.StatusTooManyRequests, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// SendPhoneOTP texts a verification code to the phone of the current user
+func (a *VerificationController) SendPhoneOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := a.verificationService.SendPhoneOTP(ctx, appcontext.UserID(ctx))
+	if err != nil {
+		err.Path = ".VerificationController->SendPhoneOTP()" + err.Path
+		switch err.Error {
+		case types.ErrPhoneNotSet, types.ErrPhoneAlreadyVerified:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnprocessableEntity, *err)
+		case types.ErrTooManyRequests:
+			response.Error(ctx, w, err.Error.Error(), http.StatusTooManyRequests, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// VerifyPhone verifies the phone of the current user with the texted code
+func (a *VerificationController) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	var err *types.Error
+	ctx := r.Context()
+
+	params := &datatransfers.VerifyPhoneParams{}
+	if err = decodeAndValidate(r, params); err != nil {
+		err.Path = ".VerificationController->VerifyPhone()" + err.Path
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+		return
+	}
+
+	var verifiedUser *models.User
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		verifiedUser, err = a.verificationService.VerifyPhoneOTP(ctx, appcontext.UserID(ctx), params.Code)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		err.Path = ".VerificationController->VerifyPhone()" + err.Path
+		switch errTransaction {
+		case types.ErrOTPInvalid:
+			response.Error(ctx, w, err.Error.Error(), http.StatusUnprocessableEntity, *err)
+		case types.ErrOTPTooManyAttempts:
+			response.Error(ctx, w, err.Error.Error(), http.StatusTooManyRequests, *err)
+		default:
+			response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, verifiedUser)
+}
+
+// NewVerificationController creates a new verification controller
+func NewVerificationController(
+	verificationService verification.ServiceInterface,
+	dataManager *data.Manager,
+) *VerificationController {
+	return &VerificationController{
+		verificationService: verificationService,
+		dataManager:         dataManager,
+	}
+}
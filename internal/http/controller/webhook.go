@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	clerkSDK "github.com/clerk/clerk-sdk-go/v2"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/clerk"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+)
+
+// maxWebhookBodySize caps the size of a webhook payload
+const maxWebhookBodySize = 1 << 20
+
+// webhookDeliveryTTL is how long a processed svix-id is remembered, longer than Svix keeps retrying
+const webhookDeliveryTTL = 48 * time.Hour
+
+// WebhookController represents the webhook controller
+type WebhookController struct {
+	userService user.ServiceInterface
+	dataManager *data.Manager
+}
+
+// ClerkWebhook receives the user events of Clerk.
+// Deliveries are verified against their Svix signature, and a svix-id that has
+// already been processed is acknowledged without being applied again.
+func (a *WebhookController) ClerkWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, errRead := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if errRead != nil {
+		response.Error(ctx, w, "Bad Request", http.StatusBadRequest, types.Error{
+			Path:    ".WebhookController->ClerkWebhook()",
+			Message: errRead.Error(),
+			Error:   errRead,
+			Type:    types.ErrTypesHandlerError,
+		})
+		return
+	}
+
+	errVerify := clerk.VerifyWebhook(config.AppConfig.ClerkWebhookSecret, r.Header, body, time.Now())
+	if errVerify != nil {
+		response.Error(ctx, w, "Unauthorized", http.StatusUnauthorized, types.Error{
+			Path:    ".WebhookController->ClerkWebhook()",
+			Message: errVerify.Error(),
+			Error:   errVerify,
+			Type:    types.ErrTypesHandlerError,
+		})
+		return
+	}
+
+	// Claim the delivery before processing so concurrent redeliveries don't both apply it
+	deliveryKey := fmt.Sprintf(constants.ClerkWebhookCacheKey, r.Header.Get("svix-id"))
+	claimed, errCache := redis.SetCacheIfNotExists(ctx, deliveryKey, time.Now().Unix(), webhookDeliveryTTL)
+	if errCache != nil {
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, types.Error{
+			Path:    ".WebhookController->ClerkWebhook()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesHandlerError,
+		})
+		return
+	}
+	if !claimed {
+		response.JSON(w, http.StatusNoContent, "")
+		return
+	}
+
+	err := a.handleClerkEvent(ctx, body)
+	if err != nil {
+		// Release the delivery so the Svix retry is processed
+		if errDelete := redis.DeleteCache(context.Background(), deliveryKey); errDelete != nil {
+			log.Printf("Failed to release webhook delivery: %v", errDelete)
+		}
+
+		err.Path = ".WebhookController->ClerkWebhook()" + err.Path
+		if err.Type == types.ErrTypesHandlerError {
+			response.Error(ctx, w, "Bad Request", http.StatusBadRequest, *err)
+			return
+		}
+		response.Error(ctx, w, "Internal Server Error", http.StatusInternalServerError, *err)
+		return
+	}
+
+	response.JSON(w, http.StatusNoContent, "")
+}
+
+// handleClerkEvent applies a verified Clerk event, event types we don't use are ignored
+func (a *WebhookController) handleClerkEvent(ctx context.Context, body []byte) *types.Error {
+	var event datatransfers.ClerkWebhookEvent
+	if errDecode := json.Unmarshal(body, &event); errDecode != nil {
+		return &types.Error{
+			Path:    ".WebhookController->handleClerkEvent()",
+			Message: errDecode.Error(),
+			Error:   errDecode,
+			Type:    types.ErrTypesHandlerError,
+		}
+	}
+
+	var err *types.Error
+	var apply func(ctx context.Context) *types.Error
+
+	switch event.Type {
+	case "user.created", "user.updated":
+		var remote clerkSDK.User
+		if errDecode := json.Unmarshal(event.Data, &remote); errDecode != nil || remote.ID == "" {
+			return webhookPayloadError(event.Type, errDecode)
+		}
+		apply = func(ctx context.Context) *types.Error {
+			_, err := a.userService.SyncFromClerk(ctx, identity.FromClerkUser(&remote))
+			return err
+		}
+	case "user.deleted":
+		var deleted clerkSDK.DeletedResource
+		if errDecode := json.Unmarshal(event.Data, &deleted); errDecode != nil || deleted.ID == "" {
+			return webhookPayloadError(event.Type, errDecode)
+		}
+		apply = func(ctx context.Context) *types.Error {
+			return a.userService.DeleteFromClerk(ctx, deleted.ID)
+		}
+	default:
+		return nil
+	}
+
+	errTransaction := a.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		err = apply(ctx)
+		if err != nil {
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		if err == nil {
+			err = types.NewError(errTransaction)
+		}
+		err.Path = ".WebhookController->handleClerkEvent()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+func webhookPayloadError(eventType string, errDecode error) *types.Error {
+	if errDecode == nil {
+		errDecode = fmt.Errorf("%s event has no user id", eventType)
+	}
+
+	return &types.Error{
+		Path:    ".WebhookController->handleClerkEvent()",
+		Message: errDecode.Error(),
+		Error:   errDecode,
+		Type:    types.ErrTypesHandlerError,
+	}
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(
+	userService user.ServiceInterface,
+	dataManager *data.Manager,
+) *WebhookController {
+	return &WebhookController{
+		userService: userService,
+		dataManager: dataManager,
+	}
+}
@@ -33,6 +33,12 @@ func MakeFieldError(field, tag string) *FieldError {
 
 // Error writes error http response and logs via Uptrace + terminal
 func Error(ctx context.Context, w http.ResponseWriter, message string, status int, err types.Error) {
+	ErrorWithCode(ctx, w, "", message, status, err)
+}
+
+// ErrorWithCode writes error http response like Error, but uses the given code
+// instead of the one derived from the status when it is not empty
+func ErrorWithCode(ctx context.Context, w http.ResponseWriter, code string, message string, status int, err types.Error) {
 	// Step 1: Log error via logger + uptrace
 	err.Log(ctx, logger.Tracer)
 
@@ -51,6 +57,15 @@ func Error(ctx context.Context, w http.ResponseWriter, message string, status in
 		errorCode = "BadRequest"
 	case http.StatusUnprocessableEntity:
 		errorCode = "ValidationError"
+	case http.StatusBadGateway:
+		errorCode = "BadGateway"
+	case http.StatusForbidden:
+		errorCode = "Forbidden"
+	case http.StatusTooManyRequests:
+		errorCode = "TooManyRequests"
+	}
+	if code != "" {
+		errorCode = code
 	}
 
 	// Step 4: Handle validation errors
@@ -61,6 +76,15 @@ func Error(ctx context.Context, w http.ResponseWriter, message string, status in
 			errorFields = append(errorFields, MakeFieldError(fieldErr.Field(), fieldErr.ActualTag()))
 		}
 	}
+	if violations, ok := err.Error.(types.FieldViolations); ok {
+		message = "Validation failed"
+		for _, violation := range violations {
+			errorFields = append(errorFields, &FieldError{
+				Field:   violation.Field,
+				Message: violation.Message,
+			})
+		}
+	}
 
 	// Step 5: Encode response
 	res := ErrorResponse{
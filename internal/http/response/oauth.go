@@ -0,0 +1,34 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// OAuthErrorResponse is the error response of RFC 6749
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthError writes an error response of the OAuth endpoints in the RFC 6749 shape and logs like Error
+func OAuthError(ctx context.Context, w http.ResponseWriter, code string, description string, status int, err types.Error) {
+	err.Log(ctx, logger.Tracer)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	res := OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("[response.OAuthError] failed to encode JSON: %v", err)
+	}
+}
@@ -13,18 +13,43 @@ import (
 	"github.com/riskibarqy/bq-account-service/config"
 	"github.com/riskibarqy/bq-account-service/internal/data"
 	"github.com/riskibarqy/bq-account-service/internal/http/controller"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/app"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/auth"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/lockout"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/mfa"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/oauth"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/passkey"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/token"
 	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/verification"
 	"github.com/rs/cors"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Server represents the http server that handles the requests
 type Server struct {
-	dataManager    *data.Manager
-	userService    user.ServiceInterface
-	userController *controller.UserController
+	dataManager       *data.Manager
+	userService       user.ServiceInterface
+	sessionService    session.ServiceInterface
+	appService        app.AppServiceInterface
+	userController    *controller.UserController
+	tokenController   *controller.TokenController
+	sessionController *controller.SessionController
+	webhookController *controller.WebhookController
+	authController    *controller.AuthController
+
+	verificationController *controller.VerificationController
+	mfaController          *controller.MFAController
+	passkeyController      *controller.PasskeyController
+	adminController        *controller.AdminController
+	appController          *controller.AppController
+	oauthController        *controller.OAuthController
 }
 
+// baseURL is the prefix of the API routes
+const baseURL = "/bq-account-service/v1"
+
 func (hs *Server) authMethod(r chi.Router, method string, path string, handler http.HandlerFunc) {
 	r.With(
 		hs.instrument(method, "/v1"+path),
@@ -38,7 +63,7 @@ func (hs *Server) compileRouter() chi.Router {
 	//
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIP(parseTrustedProxies(config.AppConfig.TrustedProxies)))
 	r.Use(middleware.Recoverer)
 	r.Use(otelhttp.NewMiddleware(config.AppConfig.AppName))
 
@@ -55,16 +80,13 @@ func (hs *Server) compileRouter() chi.Router {
 		AllowedOrigins: []string{"*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Access-Token", "X-Requested-With"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Access-Token", "X-Requested-With", "X-Device-Name"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	})
 	r.Use(cors.Handler)
 
-	// Define the base URL for the API
-	baseURL := "/bq-account-service/v1"
-
 	// Public Routes (No authorization required)
 	r.HandleFunc(baseURL+"/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -72,20 +94,105 @@ func (hs *Server) compileRouter() chi.Router {
 		// w.Write([]byte("success"))
 	})
 
-	// r.HandleFunc(baseURL+"/login", hs.userController.Login)
+	// Public keys for offline verification of our tokens by other services
+	r.Get("/.well-known/jwks.json", hs.jwks)
+	r.Get("/.well-known/openid-configuration", hs.openIDConfiguration)
+
+	r.Post(baseURL+"/login", hs.authController.Login)
+	r.Post(baseURL+"/login/mfa", hs.authController.LoginMFA)
+	r.Post(baseURL+"/login/email", hs.authController.RequestEmailLogin)
+	r.Post(baseURL+"/login/email/verify", hs.authController.VerifyEmailLogin)
+	r.Post(baseURL+"/passkeys/loginOptions", hs.passkeyController.LoginOptions)
+	r.Post(baseURL+"/passkeys/login", hs.authController.LoginPasskey)
+	r.Post(baseURL+"/password/forgot", hs.authController.ForgotPassword)
+	r.Post(baseURL+"/password/reset", hs.authController.ResetPassword)
+	r.Get(baseURL+"/verify-email", hs.verificationController.VerifyEmail)
+	r.Post(baseURL+"/token/refresh", hs.tokenController.Refresh)
+	r.Post(baseURL+"/token/revoke", hs.tokenController.Revoke)
+	r.Get(baseURL+"/oauth/authorize", hs.oauthController.Authorize)
+	r.Post(baseURL+"/oauth/token", hs.oauthController.Token)
+
+	// Webhooks, authenticated by their signature
+	r.Post(baseURL+"/webhooks/clerk", hs.webhookController.ClerkWebhook)
 
 	// Private Routes (Authorization required)
 	r.Route(baseURL+"/private", func(r chi.Router) {
-		// Middleware for authorized requests
-		r.Use(hs.authorizedOnly(hs.userService))
-
-		// Private User routes (require authorization)
-		// hs.authMethod(r, "PUT", "/users/changePassword", hs.userController.ChangePassword)
-		// hs.authMethod(r, "PUT", "/users/{userId}", hs.userController.UpdateUser)
-		hs.authMethod(r, "GET", "/users", hs.userController.ListUser)
-		// hs.authMethod(r, "GET", "/users/{userId}", hs.userController.GetUserByID)
-		// hs.authMethod(r, "POST", "/users", hs.userController.CreateUser)
-		// hs.authMethod(r, "DELETE", "/users/{userId}", hs.userController.DeleteUser)
+		r.Group(func(r chi.Router) {
+			// Middleware for authorized requests
+			r.Use(hs.authorizedOnly(hs.userService))
+
+			// Private User routes (require authorization)
+			hs.authMethod(r, "PUT", "/users/changePassword", hs.authController.ChangePassword)
+			hs.authMethod(r, "POST", "/users/resendVerification", hs.verificationController.ResendEmailVerification)
+			hs.authMethod(r, "POST", "/users/sendPhoneCode", hs.verificationController.SendPhoneOTP)
+			hs.authMethod(r, "POST", "/users/verifyPhone", hs.verificationController.VerifyPhone)
+			// hs.authMethod(r, "POST", "/users", hs.userController.CreateUser)
+
+			// Private Session routes, always scoped to the current user
+			hs.authMethod(r, "GET", "/sessions", hs.sessionController.ListSessions)
+			hs.authMethod(r, "POST", "/sessions/revokeOthers", hs.sessionController.RevokeOtherSessions)
+			hs.authMethod(r, "DELETE", "/sessions/{sessionId}", hs.sessionController.RevokeSession)
+
+			// Private MFA routes, always scoped to the current user
+			hs.authMethod(r, "POST", "/mfa/enroll", hs.mfaController.Enroll)
+			hs.authMethod(r, "POST", "/mfa/confirm", hs.mfaController.Confirm)
+			hs.authMethod(r, "POST", "/mfa/disable", hs.mfaController.Disable)
+			hs.authMethod(r, "POST", "/mfa/recoveryCodes", hs.mfaController.RegenerateRecoveryCodes)
+
+			// Private passkey routes, always scoped to the current user
+			hs.authMethod(r, "POST", "/passkeys/registerOptions", hs.passkeyController.RegistrationOptions)
+			hs.authMethod(r, "POST", "/passkeys/register", hs.passkeyController.Register)
+			hs.authMethod(r, "GET", "/passkeys", hs.passkeyController.ListPasskeys)
+			hs.authMethod(r, "PUT", "/passkeys/{passkeyId}", hs.passkeyController.RenamePasskey)
+			hs.authMethod(r, "DELETE", "/passkeys/{passkeyId}", hs.passkeyController.RemovePasskey)
+
+			// Private OAuth routes for the consent page and the apps the current user granted scopes to
+			hs.authMethod(r, "POST", "/oauth/authorize", hs.oauthController.Approve)
+			hs.authMethod(r, "GET", "/oauth/consents", hs.oauthController.ListConsents)
+			hs.authMethod(r, "DELETE", "/oauth/consents/{appId}", hs.oauthController.RevokeConsent)
+		})
+
+		// Private routes that apps may call too, with an OAuth token carrying the scope
+		r.Group(func(r chi.Router) {
+			r.Use(hs.authorizedOnly(hs.userService, allowClients("users:read")))
+
+			hs.authMethod(r, "GET", "/users", hs.userController.ListUser)
+			hs.authMethod(r, "GET", "/users/{userId}", hs.userController.GetUserByID)
+		})
+
+		// OpenID Connect userinfo, for the user itself or an app the user granted openid to
+		r.Group(func(r chi.Router) {
+			r.Use(hs.authorizedOnly(hs.userService, allowClients(oauth.ScopeOpenID), usersOnly()))
+
+			hs.authMethod(r, "GET", "/userinfo", hs.oauthController.UserInfo)
+			hs.authMethod(r, "POST", "/userinfo", hs.oauthController.UserInfo)
+		})
+
+		// Admin routes, for the users listed in ADMIN_USER_IDS
+		r.Group(func(r chi.Router) {
+			r.Use(hs.authorizedOnly(hs.userService, requireAdmin()))
+
+			hs.authMethod(r, "POST", "/admin/users/{userId}/unlock", hs.adminController.UnlockUser)
+
+			hs.authMethod(r, "GET", "/admin/apps", hs.appController.ListApps)
+			hs.authMethod(r, "POST", "/admin/apps", hs.appController.CreateApp)
+			hs.authMethod(r, "GET", "/admin/apps/{appId}", hs.appController.GetAppByID)
+			hs.authMethod(r, "PUT", "/admin/apps/{appId}", hs.appController.UpdateApp)
+			hs.authMethod(r, "DELETE", "/admin/apps/{appId}", hs.appController.DeleteApp)
+			hs.authMethod(r, "POST", "/admin/apps/{appId}/rotateSecret", hs.appController.RotateClientSecret)
+			hs.authMethod(r, "GET", "/admin/apps/{appId}/redirectUris", hs.appController.ListRedirectURIs)
+			hs.authMethod(r, "POST", "/admin/apps/{appId}/redirectUris", hs.appController.AddRedirectURI)
+			hs.authMethod(r, "DELETE", "/admin/apps/{appId}/redirectUris/{redirectUriId}", hs.appController.RemoveRedirectURI)
+		})
+
+		// Admin routes that also require a verified email
+		r.Group(func(r chi.Router) {
+			r.Use(hs.authorizedOnly(hs.userService, requireAdmin(), requireVerified()))
+
+			// Updating and deleting users is admin tooling, users can't touch accounts other than their own
+			hs.authMethod(r, "PUT", "/users/{userId}", hs.userController.UpdateUser)
+			hs.authMethod(r, "DELETE", "/users/{userId}", hs.userController.DeleteUser)
+		})
 	})
 
 	// Public Users Route
@@ -137,12 +244,44 @@ func NewServer(
 	config *config.Config,
 	dataManager *data.Manager,
 	userService user.ServiceInterface,
+	tokenService token.ServiceInterface,
+	sessionService session.ServiceInterface,
+	authService auth.ServiceInterface,
+	verificationService verification.ServiceInterface,
+	mfaService mfa.ServiceInterface,
+	passkeyService passkey.ServiceInterface,
+	lockoutService lockout.ServiceInterface,
+	appService app.AppServiceInterface,
+	oauthService oauth.ServiceInterface,
 ) *Server {
-	userController := controller.NewUserController(userService, dataManager)
+	userController := controller.NewUserController(userService, verificationService, dataManager)
+	tokenController := controller.NewTokenController(tokenService)
+	sessionController := controller.NewSessionController(sessionService)
+	webhookController := controller.NewWebhookController(userService, dataManager)
+	authController := controller.NewAuthController(authService, dataManager)
+	verificationController := controller.NewVerificationController(verificationService, dataManager)
+	mfaController := controller.NewMFAController(mfaService, dataManager)
+	passkeyController := controller.NewPasskeyController(passkeyService, dataManager)
+	adminController := controller.NewAdminController(lockoutService, dataManager)
+	appController := controller.NewAppController(appService, dataManager)
+	oauthController := controller.NewOAuthController(oauthService, dataManager)
 
 	return &Server{
-		dataManager:    dataManager,
-		userService:    userService,
-		userController: userController,
+		dataManager:       dataManager,
+		userService:       userService,
+		sessionService:    sessionService,
+		appService:        appService,
+		userController:    userController,
+		tokenController:   tokenController,
+		sessionController: sessionController,
+		webhookController: webhookController,
+		authController:    authController,
+
+		verificationController: verificationController,
+		mfaController:          mfaController,
+		passkeyController:      passkeyController,
+		adminController:        adminController,
+		appController:          appController,
+		oauthController:        oauthController,
 	}
 }
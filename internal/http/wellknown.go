@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/http/response"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/oauth"
+)
+
+// jwks publishes the public keys our tokens can be verified with
+func (hs *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, config.JWTKeySet.JWKS())
+}
+
+// openIDConfiguration publishes the OpenID Connect discovery document, every URL in it starts with OIDC_ISSUER
+func (hs *Server) openIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	issuer := config.AppConfig.OIDCIssuer
+
+	algs := []string{}
+	if signer := config.JWTKeySet.Signer(); signer != nil {
+		algs = append(algs, signer.Method.Alg())
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, &datatransfers.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + baseURL + "/oauth/authorize",
+		TokenEndpoint:                     issuer + baseURL + "/oauth/token",
+		UserinfoEndpoint:                  issuer + baseURL + "/private/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{oauth.ScopeOpenID, oauth.ScopeProfile, oauth.ScopeEmail, oauth.ScopePhone},
+		ResponseTypesSupported:            []string{oauth.ResponseTypeCode},
+		GrantTypesSupported:               []string{oauth.GrantTypeAuthorizationCode, oauth.GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{oauth.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr",
+			"name", "preferred_username", "email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	})
+}
@@ -0,0 +1,310 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/emailaddress"
+	"github.com/clerk/clerk-sdk-go/v2/phonenumber"
+	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ClerkProvider is the Clerk implementation of IdentityProvider
+type ClerkProvider struct{}
+
+// CreateUser creates a user in Clerk
+func (p *ClerkProvider) CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error) {
+	createParams := &clerkUser.CreateParams{
+		EmailAddresses: &[]string{params.Email},
+		Username:       &params.Username,
+		Password:       &params.Password,
+		FirstName:      &params.FirstName,
+		LastName:       &params.LastName,
+	}
+	if params.Phone != "" {
+		createParams.PhoneNumbers = &[]string{params.Phone}
+	}
+
+	remote, errClerk := clerkUser.Create(ctx, createParams)
+	if errClerk != nil {
+		return nil, clerkError(".ClerkProvider->CreateUser()", errClerk)
+	}
+
+	return FromClerkUser(remote), nil
+}
+
+// UpdateUser pushes the changed fields to Clerk. Email addresses and phone numbers are
+// separate Clerk resources, a new one is added as primary and the replaced one removed.
+// When one of the steps fails the steps already done are reverted before returning.
+func (p *ClerkProvider) UpdateUser(ctx context.Context, id string, params *UpdateUserParams) (*User, *types.Error) {
+	remote, errClerk := clerkUser.Get(ctx, id)
+	if errClerk != nil {
+		return nil, clerkError(".ClerkProvider->UpdateUser()", errClerk)
+	}
+
+	undo := []func(ctx context.Context) error{}
+	cleanup := []func(ctx context.Context) error{}
+	fail := func(errClerk error) (*User, *types.Error) {
+		revert(ctx, undo)
+		return nil, clerkError(".ClerkProvider->UpdateUser()", errClerk)
+	}
+
+	if params.FirstName != nil || params.LastName != nil || params.Username != nil {
+		_, errClerk = clerkUser.Update(ctx, id, &clerkUser.UpdateParams{
+			FirstName: params.FirstName,
+			LastName:  params.LastName,
+			Username:  params.Username,
+		})
+		if errClerk != nil {
+			return fail(errClerk)
+		}
+
+		undo = append(undo, func(ctx context.Context) error {
+			_, err := clerkUser.Update(ctx, id, &clerkUser.UpdateParams{
+				FirstName: remote.FirstName,
+				LastName:  remote.LastName,
+				Username:  remote.Username,
+			})
+			return err
+		})
+	}
+
+	if params.Email != nil {
+		verified, primary := true, true
+		email, errClerk := emailaddress.Create(ctx, &emailaddress.CreateParams{
+			UserID:       &id,
+			EmailAddress: params.Email,
+			Verified:     &verified,
+			Primary:      &primary,
+		})
+		if errClerk != nil {
+			return fail(errClerk)
+		}
+
+		oldEmailID := remote.PrimaryEmailAddressID
+		undo = append(undo, func(ctx context.Context) error {
+			if oldEmailID != nil {
+				if _, err := clerkUser.Update(ctx, id, &clerkUser.UpdateParams{PrimaryEmailAddressID: oldEmailID}); err != nil {
+					return err
+				}
+			}
+			_, err := emailaddress.Delete(ctx, email.ID)
+			return err
+		})
+		if oldEmailID != nil {
+			cleanup = append(cleanup, func(ctx context.Context) error {
+				_, err := emailaddress.Delete(ctx, *oldEmailID)
+				return err
+			})
+		}
+	}
+
+	if params.Phone != nil && *params.Phone != "" {
+		verified, primary := true, true
+		phone, errClerk := phonenumber.Create(ctx, &phonenumber.CreateParams{
+			UserID:      &id,
+			PhoneNumber: params.Phone,
+			Verified:    &verified,
+			Primary:     &primary,
+		})
+		if errClerk != nil {
+			return fail(errClerk)
+		}
+
+		oldPhoneID := remote.PrimaryPhoneNumberID
+		undo = append(undo, func(ctx context.Context) error {
+			if oldPhoneID != nil {
+				if _, err := clerkUser.Update(ctx, id, &clerkUser.UpdateParams{PrimaryPhoneNumberID: oldPhoneID}); err != nil {
+					return err
+				}
+			}
+			_, err := phonenumber.Delete(ctx, phone.ID)
+			return err
+		})
+		if oldPhoneID != nil {
+			cleanup = append(cleanup, func(ctx context.Context) error {
+				_, err := phonenumber.Delete(ctx, *oldPhoneID)
+				return err
+			})
+		}
+	}
+
+	// The user already points at the new email and phone, a failed cleanup only leaves a stale entry behind
+	for _, f := range cleanup {
+		if err := f(ctx); err != nil {
+			clerkError(".ClerkProvider->UpdateUser()", err).Log(ctx, logger.Tracer)
+		}
+	}
+
+	updated, errClerk := clerkUser.Get(ctx, id)
+	if errClerk != nil {
+		return nil, clerkError(".ClerkProvider->UpdateUser()", errClerk)
+	}
+
+	return FromClerkUser(updated), nil
+}
+
+// DeleteUser deletes the Clerk user, a user that is already gone counts as deleted
+func (p *ClerkProvider) DeleteUser(ctx context.Context, id string) *types.Error {
+	_, errClerk := clerkUser.Delete(ctx, id)
+	if errClerk != nil && !isClerkStatus(errClerk, http.StatusNotFound) {
+		return clerkError(".ClerkProvider->DeleteUser()", errClerk)
+	}
+
+	return nil
+}
+
+// VerifyCredentials checks the password of the user with the given email or username
+func (p *ClerkProvider) VerifyCredentials(ctx context.Context, identifier string, password string) (*User, *types.Error) {
+	listParams := &clerkUser.ListParams{}
+	if strings.Contains(identifier, "@") {
+		listParams.EmailAddresses = []string{identifier}
+	} else {
+		listParams.Usernames = []string{identifier}
+	}
+
+	users, errClerk := clerkUser.List(ctx, listParams)
+	if errClerk != nil {
+		return nil, clerkError(".ClerkProvider->VerifyCredentials()", errClerk)
+	}
+	if len(users.Users) != 1 {
+		return nil, invalidCredentials(".ClerkProvider->VerifyCredentials()")
+	}
+
+	remote := users.Users[0]
+	path, errPath := clerk.JoinPath("/users", remote.ID, "verify_password")
+	if errPath != nil {
+		return nil, clerkError(".ClerkProvider->VerifyCredentials()", errPath)
+	}
+
+	req := clerk.NewAPIRequest(http.MethodPost, path)
+	req.SetParams(&verifyPasswordParams{Password: &password})
+	result := &verifyPasswordResponse{}
+	errClerk = clerk.GetBackend().Call(ctx, req, result)
+	if errClerk != nil {
+		// Clerk answers a wrong password with a 4xx
+		var apiErr *clerk.APIErrorResponse
+		if errors.As(errClerk, &apiErr) && apiErr.HTTPStatusCode >= 400 && apiErr.HTTPStatusCode < 500 {
+			return nil, invalidCredentials(".ClerkProvider->VerifyCredentials()")
+		}
+		return nil, clerkError(".ClerkProvider->VerifyCredentials()", errClerk)
+	}
+	if !result.Verified {
+		return nil, invalidCredentials(".ClerkProvider->VerifyCredentials()")
+	}
+
+	return FromClerkUser(remote), nil
+}
+
+// SetPassword replaces the password of a Clerk user
+func (p *ClerkProvider) SetPassword(ctx context.Context, id string, password string) *types.Error {
+	_, errClerk := clerkUser.Update(ctx, id, &clerkUser.UpdateParams{Password: &password})
+	if errClerk != nil {
+		return clerkError(".ClerkProvider->SetPassword()", errClerk)
+	}
+
+	return nil
+}
+
+// GetUser fetches a user from Clerk
+func (p *ClerkProvider) GetUser(ctx context.Context, id string) (*User, *types.Error) {
+	remote, errClerk := clerkUser.Get(ctx, id)
+	if errClerk != nil {
+		if isClerkStatus(errClerk, http.StatusNotFound) {
+			return nil, &types.Error{
+				Path:    ".ClerkProvider->GetUser()",
+				Message: types.ErrNotFound.Error(),
+				Error:   types.ErrNotFound,
+				Type:    types.ErrTypesClerkError,
+			}
+		}
+		return nil, clerkError(".ClerkProvider->GetUser()", errClerk)
+	}
+
+	return FromClerkUser(remote), nil
+}
+
+// FromClerkUser maps a Clerk user, using its primary email and phone
+func FromClerkUser(remote *clerk.User) *User {
+	user := &User{ID: remote.ID, UpdatedAt: remote.UpdatedAt}
+	if remote.FirstName != nil {
+		user.FirstName = *remote.FirstName
+	}
+	if remote.LastName != nil {
+		user.LastName = *remote.LastName
+	}
+	if remote.Username != nil {
+		user.Username = *remote.Username
+	}
+
+	for _, email := range remote.EmailAddresses {
+		if remote.PrimaryEmailAddressID != nil && email.ID == *remote.PrimaryEmailAddressID {
+			user.Email = email.EmailAddress
+			user.EmailVerified = email.Verification != nil && email.Verification.Status == "verified"
+		}
+	}
+	for _, phone := range remote.PhoneNumbers {
+		if remote.PrimaryPhoneNumberID != nil && phone.ID == *remote.PrimaryPhoneNumberID {
+			user.Phone = phone.PhoneNumber
+		}
+	}
+
+	return user
+}
+
+type verifyPasswordParams struct {
+	clerk.APIParams
+	Password *string `json:"password"`
+}
+
+type verifyPasswordResponse struct {
+	clerk.APIResource
+	Verified bool `json:"verified"`
+}
+
+// revert undoes the steps in reverse order, failures are logged since the
+// original error is the one returned to the caller
+func revert(ctx context.Context, undo []func(ctx context.Context) error) {
+	ctxTimeout, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	for i := len(undo) - 1; i >= 0; i-- {
+		if err := undo[i](ctxTimeout); err != nil {
+			clerkError(".ClerkProvider->revert()", err).Log(ctx, logger.Tracer)
+		}
+	}
+}
+
+func isClerkStatus(err error, status int) bool {
+	var apiErr *clerk.APIErrorResponse
+	return errors.As(err, &apiErr) && apiErr.HTTPStatusCode == status
+}
+
+func clerkError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesClerkError,
+	}
+}
+
+func invalidCredentials(path string) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: types.ErrInvalidCredentials.Error(),
+		Error:   types.ErrInvalidCredentials,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewClerkProvider creates a new Clerk identity provider, the key is set by clerk.Init
+func NewClerkProvider() *ClerkProvider {
+	return &ClerkProvider{}
+}
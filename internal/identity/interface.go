@@ -0,0 +1,79 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Identity provider names, as configured in IDENTITY_PROVIDER and app.identity_provider
+const (
+	ProviderClerk  = "clerk"
+	ProviderLocal  = "local"
+	ProviderMemory = "memory"
+)
+
+// User represents a user as the identity provider knows it
+type User struct {
+	ID        string
+	FirstName string
+	LastName  string
+	Username  string
+	Email     string
+	Phone     string
+	// EmailVerified tells whether the provider verified the email, providers that don't verify emails leave it false
+	EmailVerified bool
+	// UpdatedAt is when the provider last changed the user in milliseconds, 0 when the provider doesn't say
+	UpdatedAt int64
+}
+
+// CreateUserParams represents the data of a new identity provider user
+type CreateUserParams struct {
+	FirstName string
+	LastName  string
+	Username  string
+	Email     string
+	Phone     string
+	Password  string
+}
+
+// UpdateUserParams represents the changed fields of an identity provider user, nil fields are left untouched
+type UpdateUserParams struct {
+	FirstName *string
+	LastName  *string
+	Username  *string
+	Email     *string
+	Phone     *string
+}
+
+// IdentityProvider represents the system of record of the user credentials.
+// DeleteUser treats an unknown user as deleted, GetUser returns types.ErrNotFound
+// for it and VerifyCredentials returns types.ErrInvalidCredentials for an unknown
+// identifier and a wrong password alike.
+type IdentityProvider interface {
+	CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error)
+	UpdateUser(ctx context.Context, id string, params *UpdateUserParams) (*User, *types.Error)
+	DeleteUser(ctx context.Context, id string) *types.Error
+	VerifyCredentials(ctx context.Context, identifier string, password string) (*User, *types.Error)
+	SetPassword(ctx context.Context, id string, password string) *types.Error
+	GetUser(ctx context.Context, id string) (*User, *types.Error)
+}
+
+// Providers holds the identity providers by name, an app picks one of them for its users
+type Providers map[string]IdentityProvider
+
+// Get returns the identity provider with the given name
+func (p Providers) Get(name string) (IdentityProvider, *types.Error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, &types.Error{
+			Path:    ".Providers->Get()",
+			Message: fmt.Sprintf("%s: %q", types.ErrUnknownIdentityProvider.Error(), name),
+			Error:   types.ErrUnknownIdentityProvider,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,253 @@
+package identity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/password"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/repository/userpassword"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// localSubjectPrefix prefixes the user IDs of the local provider, they are stored in user.clerk_id
+const localSubjectPrefix = "local_"
+
+// LocalProvider is the IdentityProvider backed by our own user and user_password tables.
+// The profile lives in the user table, the provider only owns the password hashes.
+type LocalProvider struct {
+	userStorage         user.Storage
+	userPasswordStorage userpassword.Storage
+	hasher              password.Hasher
+
+	// dummyPasswordHash is verified against when the user is unknown, so the response
+	// time doesn't tell whether an account exists
+	dummyPasswordHash string
+}
+
+// CreateUser stores the password hash of a new local user
+func (p *LocalProvider) CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error) {
+	randomID, errRandom := utils.GenerateRandomToken(16)
+	if errRandom != nil {
+		return nil, localError(".LocalProvider->CreateUser()", errRandom)
+	}
+
+	passwordHash, errHash := p.hasher.Hash(params.Password)
+	if errHash != nil {
+		return nil, localError(".LocalProvider->CreateUser()", errHash)
+	}
+
+	now := utils.Now()
+	subject := localSubjectPrefix + randomID
+	_, err := p.userPasswordStorage.Insert(ctx, &models.UserPassword{
+		Subject:      subject,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+		UpdatedAt:    &now,
+	})
+	if err != nil {
+		err.Path = ".LocalProvider->CreateUser()" + err.Path
+		return nil, err
+	}
+
+	return &User{
+		ID:        subject,
+		FirstName: params.FirstName,
+		LastName:  params.LastName,
+		Username:  params.Username,
+		Email:     params.Email,
+		Phone:     params.Phone,
+	}, nil
+}
+
+// UpdateUser has nothing to push since the user table is the profile of a local user
+func (p *LocalProvider) UpdateUser(ctx context.Context, id string, params *UpdateUserParams) (*User, *types.Error) {
+	user, err := p.GetUser(ctx, id)
+	if err != nil {
+		err.Path = ".LocalProvider->UpdateUser()" + err.Path
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteUser deletes the password of a local user, unknown users are ignored
+func (p *LocalProvider) DeleteUser(ctx context.Context, id string) *types.Error {
+	userPassword, err := p.userPasswordStorage.FindBySubject(ctx, id)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".LocalProvider->DeleteUser()" + err.Path
+		return err
+	}
+
+	err = p.userPasswordStorage.Delete(ctx, userPassword.ID)
+	if err != nil {
+		err.Path = ".LocalProvider->DeleteUser()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// VerifyCredentials checks the password of the local user with the given email or username.
+// A hash made with an outdated algorithm or parameters is replaced while the password is at hand.
+func (p *LocalProvider) VerifyCredentials(ctx context.Context, identifier string, plainPassword string) (*User, *types.Error) {
+	localUser, err := p.findUser(ctx, identifier)
+	if err != nil {
+		err.Path = ".LocalProvider->VerifyCredentials()" + err.Path
+		return nil, err
+	}
+
+	passwordHash := p.dummyPasswordHash
+	var userPassword *models.UserPassword
+	if localUser != nil {
+		userPassword, err = p.userPasswordStorage.FindBySubject(ctx, localUser.ClerkID)
+		if err != nil && err.Error != data.ErrNotFound {
+			err.Path = ".LocalProvider->VerifyCredentials()" + err.Path
+			return nil, err
+		}
+		if userPassword != nil {
+			passwordHash = userPassword.PasswordHash
+		}
+	}
+
+	matched, errVerify := p.hasher.Verify(passwordHash, plainPassword)
+	if errVerify != nil {
+		return nil, localError(".LocalProvider->VerifyCredentials()", errVerify)
+	}
+	if !matched || userPassword == nil {
+		return nil, invalidCredentials(".LocalProvider->VerifyCredentials()")
+	}
+
+	if p.hasher.NeedsRehash(userPassword.PasswordHash) {
+		// The password is right, failing to upgrade its hash shouldn't fail the login
+		if err := p.updatePasswordHash(ctx, userPassword, plainPassword); err != nil {
+			err.Path = ".LocalProvider->VerifyCredentials()" + err.Path
+			err.Log(ctx, logger.Tracer)
+		}
+	}
+
+	return fromUserModel(localUser), nil
+}
+
+// SetPassword replaces the password hash of a local user
+func (p *LocalProvider) SetPassword(ctx context.Context, id string, plainPassword string) *types.Error {
+	userPassword, err := p.userPasswordStorage.FindBySubject(ctx, id)
+	if err != nil {
+		err.Path = ".LocalProvider->SetPassword()" + err.Path
+		return err
+	}
+
+	err = p.updatePasswordHash(ctx, userPassword, plainPassword)
+	if err != nil {
+		err.Path = ".LocalProvider->SetPassword()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// updatePasswordHash hashes the password with the current hasher and stores it
+func (p *LocalProvider) updatePasswordHash(ctx context.Context, userPassword *models.UserPassword, plainPassword string) *types.Error {
+	passwordHash, errHash := p.hasher.Hash(plainPassword)
+	if errHash != nil {
+		return localError(".LocalProvider->updatePasswordHash()", errHash)
+	}
+
+	now := utils.Now()
+	userPassword.PasswordHash = passwordHash
+	userPassword.UpdatedAt = &now
+
+	_, err := p.userPasswordStorage.Update(ctx, userPassword)
+	if err != nil {
+		err.Path = ".LocalProvider->updatePasswordHash()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// GetUser fetches a local user from the user table
+func (p *LocalProvider) GetUser(ctx context.Context, id string) (*User, *types.Error) {
+	localUser, err := p.userStorage.FindByClerkID(ctx, id)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, localError(".LocalProvider->GetUser()", types.ErrNotFound)
+		}
+		err.Path = ".LocalProvider->GetUser()" + err.Path
+		return nil, err
+	}
+	if localUser.DeletedAt != nil || localUser.IdentityProvider != ProviderLocal {
+		return nil, localError(".LocalProvider->GetUser()", types.ErrNotFound)
+	}
+
+	return fromUserModel(localUser), nil
+}
+
+// findUser finds the active local user with the given email or username, nil when there is none
+func (p *LocalProvider) findUser(ctx context.Context, identifier string) (*models.User, *types.Error) {
+	find := p.userStorage.FindByUsername
+	if strings.Contains(identifier, "@") {
+		find = p.userStorage.FindByEmail
+	}
+
+	localUser, err := find(ctx, identifier)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, nil
+		}
+		err.Path = ".LocalProvider->findUser()" + err.Path
+		return nil, err
+	}
+	if localUser.IdentityProvider != ProviderLocal || !localUser.IsActive {
+		return nil, nil
+	}
+
+	return localUser, nil
+}
+
+func fromUserModel(localUser *models.User) *User {
+	firstName, lastName := utils.SplitName(localUser.Name)
+	return &User{
+		ID:        localUser.ClerkID,
+		FirstName: firstName,
+		LastName:  lastName,
+		Username:  localUser.Username,
+		Email:     localUser.Email,
+		Phone:     localUser.Phone,
+	}
+}
+
+func localError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewLocalProvider creates a new local identity provider
+func NewLocalProvider(
+	userStorage user.Storage,
+	userPasswordStorage userpassword.Storage,
+	hasher password.Hasher,
+) (*LocalProvider, error) {
+	dummyPasswordHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalProvider{
+		userStorage:         userStorage,
+		userPasswordStorage: userPasswordStorage,
+		hasher:              hasher,
+		dummyPasswordHash:   dummyPasswordHash,
+	}, nil
+}
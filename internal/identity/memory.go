@@ -0,0 +1,160 @@
+package identity
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// MemoryProvider is an in-memory IdentityProvider for tests and local development.
+// Users live as long as the process does.
+type MemoryProvider struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[string]*memoryUser
+}
+
+type memoryUser struct {
+	user         User
+	passwordHash [sha256.Size]byte
+}
+
+// CreateUser creates a user, email and username must be unique
+func (p *MemoryProvider) CreateUser(ctx context.Context, params *CreateUserParams) (*User, *types.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, existing := range p.users {
+		if strings.EqualFold(existing.user.Email, params.Email) {
+			return nil, memoryError(".MemoryProvider->CreateUser()", types.ErrEmailAlreadyExists)
+		}
+		if strings.EqualFold(existing.user.Username, params.Username) {
+			return nil, memoryError(".MemoryProvider->CreateUser()", types.ErrUsernameAlreadyExists)
+		}
+	}
+
+	p.nextID++
+	stored := &memoryUser{
+		user: User{
+			ID:        fmt.Sprintf("mem_%d", p.nextID),
+			FirstName: params.FirstName,
+			LastName:  params.LastName,
+			Username:  params.Username,
+			Email:     params.Email,
+			Phone:     params.Phone,
+		},
+		passwordHash: sha256.Sum256([]byte(params.Password)),
+	}
+	p.users[stored.user.ID] = stored
+
+	user := stored.user
+	return &user, nil
+}
+
+// UpdateUser updates the non nil fields of a user
+func (p *MemoryProvider) UpdateUser(ctx context.Context, id string, params *UpdateUserParams) (*User, *types.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, ok := p.users[id]
+	if !ok {
+		return nil, memoryError(".MemoryProvider->UpdateUser()", types.ErrNotFound)
+	}
+
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{params.FirstName, &stored.user.FirstName},
+		{params.LastName, &stored.user.LastName},
+		{params.Username, &stored.user.Username},
+		{params.Email, &stored.user.Email},
+		{params.Phone, &stored.user.Phone},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+
+	user := stored.user
+	return &user, nil
+}
+
+// DeleteUser deletes a user, unknown users are ignored
+func (p *MemoryProvider) DeleteUser(ctx context.Context, id string) *types.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.users, id)
+	return nil
+}
+
+// VerifyCredentials checks the password of the user with the given email or username
+func (p *MemoryProvider) VerifyCredentials(ctx context.Context, identifier string, password string) (*User, *types.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	passwordHash := sha256.Sum256([]byte(password))
+	for _, stored := range p.users {
+		if !strings.EqualFold(stored.user.Email, identifier) && !strings.EqualFold(stored.user.Username, identifier) {
+			continue
+		}
+		if subtle.ConstantTimeCompare(stored.passwordHash[:], passwordHash[:]) != 1 {
+			break
+		}
+
+		user := stored.user
+		return &user, nil
+	}
+
+	return nil, memoryError(".MemoryProvider->VerifyCredentials()", types.ErrInvalidCredentials)
+}
+
+// SetPassword replaces the password of a user
+func (p *MemoryProvider) SetPassword(ctx context.Context, id string, password string) *types.Error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, ok := p.users[id]
+	if !ok {
+		return memoryError(".MemoryProvider->SetPassword()", types.ErrNotFound)
+	}
+
+	stored.passwordHash = sha256.Sum256([]byte(password))
+	return nil
+}
+
+// GetUser fetches a user
+func (p *MemoryProvider) GetUser(ctx context.Context, id string) (*User, *types.Error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, ok := p.users[id]
+	if !ok {
+		return nil, memoryError(".MemoryProvider->GetUser()", types.ErrNotFound)
+	}
+
+	user := stored.user
+	return &user, nil
+}
+
+func memoryError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewMemoryProvider creates a new, empty, in-memory identity provider
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		users: map[string]*memoryUser{},
+	}
+}
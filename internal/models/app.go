@@ -1,15 +1,28 @@
 package models
 
+import "github.com/riskibarqy/bq-account-service/internal/types"
+
 // App models
 type App struct {
-	ID           int    `json:"id" db:"id"`
-	Name         string `json:"name" db:"name" validate:"required"`
-	Slug         string `json:"slug" db:"slug" validate:"required"`
-	ClientID     string `json:"clientId" db:"client_id" validate:"required"`
-	ClientSecret string `json:"clientSecret" db:"client_secret" validate:"required"`
-	CreatedAt    int    `json:"createdAt" db:"created_at"`
-	UpdatedAt    *int   `json:"updatedAt,omitempty" db:"updated_at"`
-	DeletedAt    *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+	ID        int    `json:"id" db:"id"`
+	Name      string `json:"name" db:"name" validate:"required"`
+	Slug      string `json:"slug" db:"slug" validate:"required"`
+	ClientID  string `json:"clientId" db:"client_id"`
+	CreatedAt int    `json:"createdAt" db:"created_at"`
+	UpdatedAt *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+
+	// ClientSecretHash is the sha256 of the client secret, the secret itself is only shown when it is issued.
+	// PreviousClientSecretHash is the secret before the last rotation, accepted until PreviousClientSecretExpiresAt
+	ClientSecretHash              string  `json:"-" db:"client_secret_hash"`
+	PreviousClientSecretHash      *string `json:"-" db:"previous_client_secret_hash"`
+	PreviousClientSecretExpiresAt *int    `json:"-" db:"previous_client_secret_expires_at"`
+
+	// IdentityProvider is the provider users registering through the app are created in
+	IdentityProvider string `json:"identityProvider" db:"identity_provider"`
+
+	// AllowedScopes are the scopes the app may ask for in its OAuth tokens
+	AllowedScopes types.StringArray `json:"allowedScopes" db:"allowed_scopes"`
 }
 
 func (u *App) ForPublic() {
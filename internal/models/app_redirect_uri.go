@@ -0,0 +1,11 @@
+package models
+
+// AppRedirectURI models, a redirect URI registered for an app. Authorization requests must name one of them exactly.
+type AppRedirectURI struct {
+	ID          int    `json:"id" db:"id"`
+	AppID       int    `json:"appId" db:"app_id"`
+	RedirectURI string `json:"redirectUri" db:"redirect_uri"`
+	CreatedAt   int    `json:"createdAt" db:"created_at"`
+	UpdatedAt   *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt   *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
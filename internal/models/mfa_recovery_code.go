@@ -0,0 +1,12 @@
+package models
+
+// MFARecoveryCode models, a single use code that stands in for a TOTP code when the authenticator is lost
+type MFARecoveryCode struct {
+	ID        int    `json:"id" db:"id"`
+	UserID    int    `json:"userId" db:"user_id"`
+	CodeHash  string `json:"-" db:"code_hash"`
+	UsedAt    *int   `json:"usedAt,omitempty" db:"used_at"`
+	CreatedAt int    `json:"createdAt" db:"created_at"`
+	UpdatedAt *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/riskibarqy/bq-account-service/internal/types"
+
+// OAuthConsent models, the scopes a user granted an app through the authorization code flow
+type OAuthConsent struct {
+	ID        int               `json:"id" db:"id"`
+	UserID    int               `json:"userId" db:"user_id"`
+	AppID     int               `json:"appId" db:"app_id"`
+	Scopes    types.StringArray `json:"scopes" db:"scopes"`
+	CreatedAt int               `json:"createdAt" db:"created_at"`
+	UpdatedAt *int              `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt *int              `json:"deletedAt,omitempty" db:"deleted_at"`
+
+	App *App `json:"app,omitempty" db:"-"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// PasswordResetToken models, a single use token emailed to reset a forgotten password
+type PasswordResetToken struct {
+	ID        int    `json:"id" db:"id"`
+	UserID    int    `json:"userId" db:"user_id"`
+	TokenHash string `json:"-" db:"token_hash"`
+	ExpiresAt int    `json:"expiresAt" db:"expires_at"`
+	UsedAt    *int   `json:"usedAt,omitempty" db:"used_at"`
+	CreatedAt int    `json:"createdAt" db:"created_at"`
+	UpdatedAt *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// RefreshToken models
+type RefreshToken struct {
+	ID        int    `json:"id" db:"id"`
+	UserID    int    `json:"userId" db:"user_id"`
+	FamilyID  string `json:"familyId" db:"family_id"`
+	SessionID string `json:"sessionId" db:"session_id"`
+	TokenHash string `json:"-" db:"token_hash"`
+	ExpiresAt int    `json:"expiresAt" db:"expires_at"`
+	RotatedAt *int   `json:"rotatedAt,omitempty" db:"rotated_at"`
+	RevokedAt *int   `json:"revokedAt,omitempty" db:"revoked_at"`
+	CreatedAt int    `json:"createdAt" db:"created_at"`
+	UpdatedAt *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/riskibarqy/bq-account-service/internal/types"
+
+// SecurityEvent models, an append only audit trail of security relevant account changes
+type SecurityEvent struct {
+	ID        int            `json:"id" db:"id"`
+	UserID    *int           `json:"userId,omitempty" db:"user_id"`
+	Type      string         `json:"type" db:"type"`
+	IP        string         `json:"ip" db:"ip"`
+	UserAgent string         `json:"userAgent" db:"user_agent"`
+	Metadata  types.Metadata `json:"metadata" db:"metadata"`
+	CreatedAt int            `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Session models, sessions live in Redis and are not persisted in the database
+type Session struct {
+	ID         string `json:"id"`
+	UserID     int    `json:"userId"`
+	Device     string `json:"device"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  int    `json:"createdAt"`
+	LastSeenAt int    `json:"lastSeenAt"`
+	Current    bool   `json:"current"`
+
+	// AuthMethods are how the user signed in, as the amr values of RFC 8176
+	AuthMethods []string `json:"authMethods,omitempty"`
+}
@@ -13,9 +13,19 @@ type User struct {
 	CreatedAt  int    `json:"createdAt" db:"created_at"`
 	UpdatedAt  *int   `json:"updatedAt,omitempty" db:"updated_at"`
 	DeletedAt  *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+
+	// IdentityProvider holds the credentials of the user, ClerkID is the user ID within it
+	IdentityProvider string `json:"identityProvider" db:"identity_provider"`
+
+	// IsPhoneVerified is reset whenever the phone changes
+	IsPhoneVerified bool `json:"isPhoneVerified" db:"is_phone_verified"`
+
+	// ClerkUpdatedAt is the updated_at in milliseconds of the Clerk user the row was last synced from
+	ClerkUpdatedAt int64 `json:"clerkUpdatedAt,omitempty" db:"clerk_updated_at"`
 }
 
 func (u *User) ForPublic() {
 	u.UpdatedAt = nil
 	u.DeletedAt = nil
+	u.ClerkUpdatedAt = 0
 }
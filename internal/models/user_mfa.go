@@ -0,0 +1,13 @@
+package models
+
+// UserMFA models, the TOTP authenticator of a user
+type UserMFA struct {
+	ID           int    `json:"id" db:"id"`
+	UserID       int    `json:"userId" db:"user_id"`
+	Secret       string `json:"-" db:"secret"`
+	ConfirmedAt  *int   `json:"confirmedAt,omitempty" db:"confirmed_at"`
+	LastUsedStep int64  `json:"-" db:"last_used_step"`
+	CreatedAt    int    `json:"createdAt" db:"created_at"`
+	UpdatedAt    *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt    *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// UserPassword models, the password hash of a user of the local identity provider
+type UserPassword struct {
+	ID           int    `json:"id" db:"id"`
+	Subject      string `json:"subject" db:"subject"`
+	PasswordHash string `json:"-" db:"password_hash"`
+	CreatedAt    int    `json:"createdAt" db:"created_at"`
+	UpdatedAt    *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt    *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
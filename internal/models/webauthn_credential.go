@@ -0,0 +1,18 @@
+package models
+
+// WebAuthnCredential models, a passkey registered by a user
+type WebAuthnCredential struct {
+	ID             int    `json:"id" db:"id"`
+	UserID         int    `json:"userId" db:"user_id"`
+	CredentialID   string `json:"credentialId" db:"credential_id"`
+	PublicKey      string `json:"-" db:"public_key"`
+	SignCount      int64  `json:"-" db:"sign_count"`
+	AAGUID         string `json:"aaguid" db:"aaguid"`
+	Transports     string `json:"transports" db:"transports"`
+	BackupEligible bool   `json:"backupEligible" db:"backup_eligible"`
+	Name           string `json:"name" db:"name"`
+	LastUsedAt     *int   `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	CreatedAt      int    `json:"createdAt" db:"created_at"`
+	UpdatedAt      *int   `json:"updatedAt,omitempty" db:"updated_at"`
+	DeletedAt      *int   `json:"deletedAt,omitempty" db:"deleted_at"`
+}
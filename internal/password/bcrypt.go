@@ -0,0 +1,64 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptCost is the cost of new bcrypt hashes, above bcrypt.DefaultCost
+const BcryptCost = 12
+
+// BcryptHasher hashes passwords with bcrypt, it is kept for the hashes written before argon2id
+type BcryptHasher struct {
+	cost int
+}
+
+// Hash hashes the password, bcrypt generates the salt itself
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Verify checks the password against a hash of any supported algorithm
+func (h *BcryptHasher) Verify(encoded string, password string) (bool, error) {
+	return verify(encoded, password)
+}
+
+// NeedsRehash tells whether the hash isn't bcrypt with the current cost
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcryptHash(encoded) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func verifyBcrypt(encoded string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// NewBcryptHasher creates a new bcrypt hasher
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{
+		cost: cost,
+	}
+}
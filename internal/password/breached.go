@@ -0,0 +1,184 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// breachedPrefixLength is the length of the SHA-1 prefixes, the same 5 hex characters the
+// Have I Been Pwned range API buckets by
+const breachedPrefixLength = 5
+
+// breachedScanSize is how small the binary search narrows a sorted list down before reading it line by line
+const breachedScanSize = 64 * 1024
+
+// maxBreachedLineLength bounds a hash, its breach count and the line ending
+const maxBreachedLineLength = 128
+
+// BreachedList is an offline list of breached password SHA-1 hashes kept on disk, only the lines around
+// the hash of a checked password are read. It is either a directory of prefix buckets, a file per 5 hex
+// character prefix named like "21BD1.txt" holding the rest of the hashes as the range API answers them,
+// or a single file sorted by hash like the "ordered by hash" download, which is binary searched.
+type BreachedList struct {
+	path      string
+	bucketed  bool
+	sortedLen int64
+}
+
+// Contains tells whether the password is on the list. A list that can't be read is logged and
+// treated as not containing the password, so a broken disk doesn't stop every password change.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	var found bool
+	var err error
+	if l.bucketed {
+		found, err = l.searchBucket(hash)
+	} else {
+		found, err = l.searchSorted(hash)
+	}
+	if err != nil {
+		log.Printf("[Password] Failed to check the breached list: %v", err)
+		return false
+	}
+
+	return found
+}
+
+// searchBucket reads the bucket of the hash prefix line by line, a missing bucket has no hashes
+func (l *BreachedList) searchBucket(hash string) (bool, error) {
+	file, err := os.Open(filepath.Join(l.path, hash[:breachedPrefixLength]+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	suffix := hash[breachedPrefixLength:]
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if breachedHash(scanner.Bytes()) == suffix {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// searchSorted binary searches the sorted file for the line of the hash, keeping lo on the start of a line
+// and the line of the hash, if there is one, starting before hi
+func (l *BreachedList) searchSorted(hash string) (bool, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	lo, hi := int64(0), l.sortedLen
+	for hi-lo > breachedScanSize {
+		mid := lo + (hi-lo)/2
+		start, line, err := lineAfter(file, mid)
+		if err != nil {
+			return false, err
+		}
+
+		switch current := breachedHash(line); {
+		case start >= hi:
+			hi = mid
+		case current == hash:
+			return true, nil
+		case current < hash:
+			lo = start
+		default:
+			hi = start
+		}
+	}
+
+	scanner := bufio.NewScanner(io.NewSectionReader(file, lo, l.sortedLen-lo))
+	for read := lo; read < hi && scanner.Scan(); read += int64(len(scanner.Bytes())) + 1 {
+		if current := breachedHash(scanner.Bytes()); current == hash {
+			return true, nil
+		} else if current > hash {
+			return false, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// lineAfter returns the first line starting at or after offset and where it starts
+func lineAfter(file *os.File, offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// Starting a byte early finds the line ending that makes offset itself the start of a line
+		start = offset - 1
+	}
+
+	buf := make([]byte, 2*maxBreachedLineLength)
+	n, err := file.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	buf = buf[:n]
+
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			return start + int64(n), nil, nil
+		}
+		start += int64(newline) + 1
+		buf = buf[newline+1:]
+	}
+	if end := bytes.IndexByte(buf, '\n'); end >= 0 {
+		buf = buf[:end]
+	}
+
+	return start, buf, nil
+}
+
+// breachedHash is the uppercase hash of a line, without the breach count after the colon
+func breachedHash(line []byte) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(string(line)), ":")
+	return strings.ToUpper(hash)
+}
+
+// LoadBreachedList opens a list of breached password hashes, a directory of prefix buckets or a file sorted
+// by hash with one uppercase SHA-1 hash per line. A breach count after a colon, as in the Have I Been Pwned
+// downloads, is ignored. Only the first line is read to check the format, the list stays on disk.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &BreachedList{path: path, bucketed: true}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, line, err := lineAfter(file, 0)
+	if err != nil {
+		return nil, err
+	}
+	hash := breachedHash(line)
+	if _, errHex := hex.DecodeString(hash); errHex != nil || len(hash) != sha1.Size*2 {
+		return nil, fmt.Errorf("%s:1: not a SHA-1 hash", path)
+	}
+
+	return &BreachedList{path: path, sortedLen: info.Size()}, nil
+}
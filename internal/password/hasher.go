@@ -0,0 +1,53 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Hashing algorithm names, as configured in PASSWORD_HASHER
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hashing algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Hasher hashes passwords into self describing strings that carry the algorithm and its parameters
+type Hasher interface {
+	// Hash hashes the password with a fresh random salt
+	Hash(password string) (string, error)
+	// Verify checks the password against a hash of any supported algorithm
+	Verify(encoded string, password string) (bool, error)
+	// NeedsRehash tells whether the hash was made with another algorithm or other parameters
+	NeedsRehash(encoded string) bool
+}
+
+// NewHasher creates the hasher of the given algorithm
+func NewHasher(algorithm string, argon2idParams *Argon2idParams) (Hasher, error) {
+	switch algorithm {
+	case AlgorithmArgon2id:
+		return NewArgon2idHasher(argon2idParams), nil
+	case AlgorithmBcrypt:
+		return NewBcryptHasher(BcryptCost), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// verify checks the password against the hash, whichever algorithm made it, so hashes
+// stay usable after the configured algorithm changes
+func verify(encoded string, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(encoded, password)
+	case isBcryptHash(encoded):
+		return verifyBcrypt(encoded, password)
+	default:
+		return false, ErrMalformedHash
+	}
+}
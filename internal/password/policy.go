@@ -0,0 +1,97 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+const (
+	// MaxLength keeps hashing cheap and within the 72 bytes bcrypt reads
+	MaxLength = 72
+
+	// minPersonalLength keeps very short names from rejecting most passwords
+	minPersonalLength = 3
+)
+
+// Policy is the set of rules a new password has to follow
+type Policy struct {
+	MinLength int
+	// MinCharacterClasses is how many of lowercase letters, uppercase letters, digits and symbols are required
+	MinCharacterClasses int
+	// Breached rejects known breached passwords, nil skips the check
+	Breached *BreachedList
+}
+
+// PolicyUser is what a password may not contain of the user it belongs to
+type PolicyUser struct {
+	Name     string
+	Email    string
+	Username string
+}
+
+// Check returns a violation for every rule the password breaks, nil when it follows the policy
+func (p *Policy) Check(field string, password string, user *PolicyUser) types.FieldViolations {
+	var violations types.FieldViolations
+	violate := func(message string) {
+		violations = append(violations, &types.FieldViolation{Field: field, Message: message})
+	}
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violate(fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > MaxLength {
+		violate(fmt.Sprintf("must be at most %d bytes long", MaxLength))
+	}
+	if characterClasses(password) < p.MinCharacterClasses {
+		violate(fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharacterClasses))
+	}
+
+	if user != nil {
+		lowered := strings.ToLower(password)
+		emailLocalPart, _, _ := strings.Cut(user.Email, "@")
+		for _, personal := range []struct {
+			values []string
+			name   string
+		}{
+			{strings.Fields(user.Name), "name"},
+			{[]string{emailLocalPart}, "email"},
+			{[]string{user.Username}, "username"},
+		} {
+			for _, value := range personal.values {
+				if len([]rune(value)) >= minPersonalLength && strings.Contains(lowered, strings.ToLower(value)) {
+					violate("must not contain your " + personal.name)
+					break
+				}
+			}
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violate("has appeared in a data breach, choose another one")
+	}
+
+	return violations
+}
+
+// characterClasses counts the character classes used in the password
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
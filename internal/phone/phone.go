@@ -0,0 +1,101 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalid       = errors.New("invalid phone number")
+	ErrUnknownRegion = errors.New("unknown phone region")
+)
+
+// E.164 numbers are at most 15 digits including the country code
+const (
+	minE164Digits = 7
+	maxE164Digits = 15
+)
+
+// Normalize parses a phone number written in any of the usual ways, "+62 812-3456-789",
+// "0062 812 3456 789" or the national "0812 3456 789", into its E.164 form "+628123456789".
+// Numbers without a country code are read as numbers of defaultRegion, an ISO 3166 alpha-2 code.
+func Normalize(raw string, defaultRegion string) (string, error) {
+	digits, international, err := strip(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if international {
+		return normalizeInternational(digits)
+	}
+
+	region, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return "", ErrUnknownRegion
+	}
+
+	// The trunk prefix is only dialed within the country
+	national := strings.TrimPrefix(digits, region.trunkPrefix)
+	if !region.validLength(national) {
+		return "", ErrInvalid
+	}
+
+	return "+" + region.callingCode + national, nil
+}
+
+// normalizeInternational validates a number that carries its country code, numbers of regions
+// we have no rules for only get the generic E.164 checks
+func normalizeInternational(digits string) (string, error) {
+	if digits[0] == '0' || len(digits) < minE164Digits || len(digits) > maxE164Digits {
+		return "", ErrInvalid
+	}
+
+	for _, region := range regions {
+		if !strings.HasPrefix(digits, region.callingCode) {
+			continue
+		}
+
+		// Some people keep the trunk prefix after the country code, "+62 0812..."
+		national := digits[len(region.callingCode):]
+		if region.trunkPrefix != "" && strings.HasPrefix(national, region.trunkPrefix) && !region.validLength(national) {
+			national = strings.TrimPrefix(national, region.trunkPrefix)
+		}
+		if !region.validLength(national) {
+			return "", ErrInvalid
+		}
+
+		return "+" + region.callingCode + national, nil
+	}
+
+	return "+" + digits, nil
+}
+
+// strip drops the formatting characters people put in phone numbers and tells whether
+// the number starts with an international prefix, "+" or "00"
+func strip(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+	raw = strings.TrimPrefix(raw, "+")
+
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, ErrInvalid
+		}
+	}
+
+	result := digits.String()
+	if !international && strings.HasPrefix(result, "00") {
+		international = true
+		result = result[2:]
+	}
+	if result == "" {
+		return "", false, ErrInvalid
+	}
+
+	return result, international, nil
+}
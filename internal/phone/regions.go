@@ -0,0 +1,30 @@
+package phone
+
+// region holds how the numbers of a country are dialed. The national number lengths are
+// deliberately loose, they only catch numbers that can't be right.
+type region struct {
+	callingCode string
+	trunkPrefix string
+	minLength   int
+	maxLength   int
+}
+
+func (r region) validLength(national string) bool {
+	return len(national) >= r.minLength && len(national) <= r.maxLength && national[0] != '0'
+}
+
+// regions are keyed by ISO 3166 alpha-2 code
+var regions = map[string]region{
+	"ID": {callingCode: "62", trunkPrefix: "0", minLength: 6, maxLength: 12},
+	"MY": {callingCode: "60", trunkPrefix: "0", minLength: 7, maxLength: 10},
+	"SG": {callingCode: "65", minLength: 8, maxLength: 8},
+	"PH": {callingCode: "63", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"TH": {callingCode: "66", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"VN": {callingCode: "84", trunkPrefix: "0", minLength: 7, maxLength: 10},
+	"IN": {callingCode: "91", trunkPrefix: "0", minLength: 10, maxLength: 10},
+	"JP": {callingCode: "81", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"AU": {callingCode: "61", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"GB": {callingCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"DE": {callingCode: "49", trunkPrefix: "0", minLength: 6, maxLength: 13},
+	"US": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+}
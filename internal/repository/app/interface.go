@@ -12,6 +12,8 @@ import (
 type Storage interface {
 	FindAll(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.App, *types.Error)
 	FindByID(ctx context.Context, appID int) (*models.App, *types.Error)
+	FindBySlug(ctx context.Context, slug string) (*models.App, *types.Error)
+	FindByClientID(ctx context.Context, clientID string) (*models.App, *types.Error)
 	Insert(ctx context.Context, app *models.App) (*models.App, *types.Error)
 	Update(ctx context.Context, app *models.App) (*models.App, *types.Error)
 	Delete(ctx context.Context, appID int) *types.Error
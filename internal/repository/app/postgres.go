@@ -66,6 +66,32 @@ func (s *AppRepository) FindByID(ctx context.Context, appID int) (*models.App, *
 	return app, nil
 }
 
+// FindBySlug find app by its slug, deleted apps included since they keep their slug
+func (s *AppRepository) FindBySlug(ctx context.Context, slug string) (*models.App, *types.Error) {
+	app := &models.App{}
+	err := s.Storage.Single(ctx, app, `"slug" = :slug`, map[string]interface{}{
+		"slug": slug,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return app, nil
+}
+
+// FindByClientID find an app that isn't deleted by its client id
+func (s *AppRepository) FindByClientID(ctx context.Context, clientID string) (*models.App, *types.Error) {
+	app := &models.App{}
+	err := s.Storage.Single(ctx, app, `"client_id" = :clientId AND "deleted_at" IS NULL`, map[string]interface{}{
+		"clientId": clientID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return app, nil
+}
+
 // Insert insert app
 func (s *AppRepository) Insert(ctx context.Context, app *models.App) (*models.App, *types.Error) {
 	err := s.Storage.Insert(ctx, app)
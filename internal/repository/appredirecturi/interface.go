@@ -0,0 +1,17 @@
+package appredirecturi
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the app redirect URI storage interface
+type Storage interface {
+	FindByID(ctx context.Context, redirectURIID int) (*models.AppRedirectURI, *types.Error)
+	FindByAppID(ctx context.Context, appID int) ([]*models.AppRedirectURI, *types.Error)
+	FindByAppIDAndURI(ctx context.Context, appID int, redirectURI string) (*models.AppRedirectURI, *types.Error)
+	Insert(ctx context.Context, redirectURI *models.AppRedirectURI) (*models.AppRedirectURI, *types.Error)
+	Delete(ctx context.Context, redirectURIID int) *types.Error
+}
@@ -0,0 +1,81 @@
+package appredirecturi
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// AppRedirectURIRepository implements the app redirect URI storage service interface
+type AppRedirectURIRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByID find app redirect URI by ID
+func (s *AppRedirectURIRepository) FindByID(ctx context.Context, redirectURIID int) (*models.AppRedirectURI, *types.Error) {
+	redirectURI := &models.AppRedirectURI{}
+	err := s.Storage.FindByID(ctx, redirectURI, redirectURIID)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return redirectURI, nil
+}
+
+// FindByAppID find the redirect URIs of the app, oldest first
+func (s *AppRedirectURIRepository) FindByAppID(ctx context.Context, appID int) ([]*models.AppRedirectURI, *types.Error) {
+	redirectURIs := []*models.AppRedirectURI{}
+	err := s.Storage.Where(ctx, &redirectURIs, `"app_id" = :appId AND "deleted_at" IS NULL ORDER BY "id"`, map[string]interface{}{
+		"appId": appID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return redirectURIs, nil
+}
+
+// FindByAppIDAndURI find a redirect URI of the app that is exactly the given one
+func (s *AppRedirectURIRepository) FindByAppIDAndURI(ctx context.Context, appID int, redirectURI string) (*models.AppRedirectURI, *types.Error) {
+	result := &models.AppRedirectURI{}
+	err := s.Storage.Single(ctx, result, `"app_id" = :appId AND "redirect_uri" = :redirectUri AND "deleted_at" IS NULL`, map[string]interface{}{
+		"appId":       appID,
+		"redirectUri": redirectURI,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return result, nil
+}
+
+// Insert insert app redirect URI
+func (s *AppRedirectURIRepository) Insert(ctx context.Context, redirectURI *models.AppRedirectURI) (*models.AppRedirectURI, *types.Error) {
+	err := s.Storage.Insert(ctx, redirectURI)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return redirectURI, nil
+}
+
+// Delete delete an app redirect URI
+func (s *AppRedirectURIRepository) Delete(ctx context.Context, redirectURIID int) *types.Error {
+	err := s.Storage.Delete(ctx, redirectURIID)
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewAppRedirectURIRepository creates new app redirect URI repository service
+func NewAppRedirectURIRepository(
+	storage data.GenericStorage,
+) *AppRedirectURIRepository {
+	return &AppRedirectURIRepository{
+		Storage: storage,
+	}
+}
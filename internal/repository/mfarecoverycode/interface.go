@@ -0,0 +1,15 @@
+package mfarecoverycode
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the MFA recovery code storage interface
+type Storage interface {
+	Insert(ctx context.Context, recoveryCode *models.MFARecoveryCode) (*models.MFARecoveryCode, *types.Error)
+	MarkUsed(ctx context.Context, userID int, codeHash string) (bool, *types.Error)
+	DeleteByUserID(ctx context.Context, userID int) *types.Error
+}
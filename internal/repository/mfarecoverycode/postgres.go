@@ -0,0 +1,70 @@
+package mfarecoverycode
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// MFARecoveryCodeRepository implements the MFA recovery code storage service interface
+type MFARecoveryCodeRepository struct {
+	Storage data.GenericStorage
+}
+
+// Insert insert MFA recovery code
+func (s *MFARecoveryCodeRepository) Insert(ctx context.Context, recoveryCode *models.MFARecoveryCode) (*models.MFARecoveryCode, *types.Error) {
+	err := s.Storage.Insert(ctx, recoveryCode)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return recoveryCode, nil
+}
+
+// MarkUsed marks the unused recovery code of the user with the given hash as used,
+// it returns false when there is no such code
+func (s *MFARecoveryCodeRepository) MarkUsed(ctx context.Context, userID int, codeHash string) (bool, *types.Error) {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "mfa_recovery_code" SET "used_at" = :now, "updated_at" = :now
+		WHERE "user_id" = :userId AND "code_hash" = :codeHash AND "used_at" IS NULL AND "deleted_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"userId":   userID,
+		"codeHash": codeHash,
+		"now":      utils.Now(),
+	})
+	if err != nil {
+		return false, types.NewError(err)
+	}
+
+	return len(ids) > 0, nil
+}
+
+// DeleteByUserID soft deletes every recovery code of the user
+func (s *MFARecoveryCodeRepository) DeleteByUserID(ctx context.Context, userID int) *types.Error {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "mfa_recovery_code" SET "deleted_at" = :now
+		WHERE "user_id" = :userId AND "deleted_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"userId": userID,
+		"now":    utils.Now(),
+	})
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewMFARecoveryCodeRepository creates new MFA recovery code repository service
+func NewMFARecoveryCodeRepository(
+	storage data.GenericStorage,
+) *MFARecoveryCodeRepository {
+	return &MFARecoveryCodeRepository{
+		Storage: storage,
+	}
+}
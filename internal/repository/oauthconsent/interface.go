@@ -0,0 +1,17 @@
+package oauthconsent
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the OAuth consent storage interface
+type Storage interface {
+	FindByUserID(ctx context.Context, userID int) ([]*models.OAuthConsent, *types.Error)
+	FindByUserIDAndAppID(ctx context.Context, userID int, appID int) (*models.OAuthConsent, *types.Error)
+	Insert(ctx context.Context, consent *models.OAuthConsent) (*models.OAuthConsent, *types.Error)
+	Update(ctx context.Context, consent *models.OAuthConsent) (*models.OAuthConsent, *types.Error)
+	Delete(ctx context.Context, consentID int) *types.Error
+}
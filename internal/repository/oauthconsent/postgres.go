@@ -0,0 +1,80 @@
+package oauthconsent
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// OAuthConsentRepository implements the OAuth consent storage service interface
+type OAuthConsentRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByUserID find the consents of the user, oldest first
+func (s *OAuthConsentRepository) FindByUserID(ctx context.Context, userID int) ([]*models.OAuthConsent, *types.Error) {
+	consents := []*models.OAuthConsent{}
+	err := s.Storage.Where(ctx, &consents, `"user_id" = :userId AND "deleted_at" IS NULL ORDER BY "id"`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return consents, nil
+}
+
+// FindByUserIDAndAppID find the consent the user gave the app
+func (s *OAuthConsentRepository) FindByUserIDAndAppID(ctx context.Context, userID int, appID int) (*models.OAuthConsent, *types.Error) {
+	consent := &models.OAuthConsent{}
+	err := s.Storage.Single(ctx, consent, `"user_id" = :userId AND "app_id" = :appId AND "deleted_at" IS NULL`, map[string]interface{}{
+		"userId": userID,
+		"appId":  appID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return consent, nil
+}
+
+// Insert insert OAuth consent
+func (s *OAuthConsentRepository) Insert(ctx context.Context, consent *models.OAuthConsent) (*models.OAuthConsent, *types.Error) {
+	err := s.Storage.Insert(ctx, consent)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return consent, nil
+}
+
+// Update update OAuth consent
+func (s *OAuthConsentRepository) Update(ctx context.Context, consent *models.OAuthConsent) (*models.OAuthConsent, *types.Error) {
+	err := s.Storage.Update(ctx, consent)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return consent, nil
+}
+
+// Delete delete an OAuth consent
+func (s *OAuthConsentRepository) Delete(ctx context.Context, consentID int) *types.Error {
+	err := s.Storage.Delete(ctx, consentID)
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewOAuthConsentRepository creates new OAuth consent repository service
+func NewOAuthConsentRepository(
+	storage data.GenericStorage,
+) *OAuthConsentRepository {
+	return &OAuthConsentRepository{
+		Storage: storage,
+	}
+}
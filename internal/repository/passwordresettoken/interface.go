@@ -0,0 +1,16 @@
+package passwordresettoken
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the password reset token storage interface
+type Storage interface {
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, *types.Error)
+	Insert(ctx context.Context, resetToken *models.PasswordResetToken) (*models.PasswordResetToken, *types.Error)
+	MarkUsed(ctx context.Context, resetTokenID int) (bool, *types.Error)
+	RevokeByUserID(ctx context.Context, userID int) *types.Error
+}
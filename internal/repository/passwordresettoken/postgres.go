@@ -0,0 +1,82 @@
+package passwordresettoken
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// PasswordResetTokenRepository implements the password reset token storage service interface
+type PasswordResetTokenRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByTokenHash find password reset token by the hash of the emailed token
+func (s *PasswordResetTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, *types.Error) {
+	resetToken := &models.PasswordResetToken{}
+	err := s.Storage.Single(ctx, resetToken, `"token_hash" = :tokenHash AND "deleted_at" IS NULL`, map[string]interface{}{
+		"tokenHash": tokenHash,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return resetToken, nil
+}
+
+// Insert insert password reset token
+func (s *PasswordResetTokenRepository) Insert(ctx context.Context, resetToken *models.PasswordResetToken) (*models.PasswordResetToken, *types.Error) {
+	err := s.Storage.Insert(ctx, resetToken)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return resetToken, nil
+}
+
+// MarkUsed marks the password reset token as used, it returns false when the token
+// was already used so concurrent resets can't both succeed
+func (s *PasswordResetTokenRepository) MarkUsed(ctx context.Context, resetTokenID int) (bool, *types.Error) {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "password_reset_token" SET "used_at" = :now, "updated_at" = :now
+		WHERE "id" = :id AND "used_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"id":  resetTokenID,
+		"now": utils.Now(),
+	})
+	if err != nil {
+		return false, types.NewError(err)
+	}
+
+	return len(ids) > 0, nil
+}
+
+// RevokeByUserID marks every unused password reset token of the user as used
+func (s *PasswordResetTokenRepository) RevokeByUserID(ctx context.Context, userID int) *types.Error {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "password_reset_token" SET "used_at" = :now, "updated_at" = :now
+		WHERE "user_id" = :userId AND "used_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"userId": userID,
+		"now":    utils.Now(),
+	})
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewPasswordResetTokenRepository creates new password reset token repository service
+func NewPasswordResetTokenRepository(
+	storage data.GenericStorage,
+) *PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{
+		Storage: storage,
+	}
+}
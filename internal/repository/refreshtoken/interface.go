@@ -0,0 +1,17 @@
+package refreshtoken
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the refresh token storage interface
+type Storage interface {
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, *types.Error)
+	Insert(ctx context.Context, refreshToken *models.RefreshToken) (*models.RefreshToken, *types.Error)
+	MarkRotated(ctx context.Context, refreshTokenID int) (bool, *types.Error)
+	RevokeFamily(ctx context.Context, familyID string) *types.Error
+	RevokeByUserID(ctx context.Context, userID int) *types.Error
+}
@@ -0,0 +1,99 @@
+package refreshtoken
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// RefreshTokenRepository implements the refresh token storage service interface
+type RefreshTokenRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByTokenHash find refresh token by the hash of the opaque token
+func (s *RefreshTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, *types.Error) {
+	refreshToken := &models.RefreshToken{}
+	err := s.Storage.Single(ctx, refreshToken, `"token_hash" = :tokenHash AND "deleted_at" IS NULL`, map[string]interface{}{
+		"tokenHash": tokenHash,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return refreshToken, nil
+}
+
+// Insert insert refresh token
+func (s *RefreshTokenRepository) Insert(ctx context.Context, refreshToken *models.RefreshToken) (*models.RefreshToken, *types.Error) {
+	err := s.Storage.Insert(ctx, refreshToken)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return refreshToken, nil
+}
+
+// MarkRotated marks the refresh token as used, it returns false when the token
+// was already rotated or revoked so concurrent refreshes can't both succeed
+func (s *RefreshTokenRepository) MarkRotated(ctx context.Context, refreshTokenID int) (bool, *types.Error) {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "refresh_token" SET "rotated_at" = :now, "updated_at" = :now
+		WHERE "id" = :id AND "rotated_at" IS NULL AND "revoked_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"id":  refreshTokenID,
+		"now": utils.Now(),
+	})
+	if err != nil {
+		return false, types.NewError(err)
+	}
+
+	return len(ids) > 0, nil
+}
+
+// RevokeFamily revokes every refresh token rotated from the same login
+func (s *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) *types.Error {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "refresh_token" SET "revoked_at" = :now, "updated_at" = :now
+		WHERE "family_id" = :familyId AND "revoked_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"familyId": familyID,
+		"now":      utils.Now(),
+	})
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// RevokeByUserID revokes every refresh token of the user
+func (s *RefreshTokenRepository) RevokeByUserID(ctx context.Context, userID int) *types.Error {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "refresh_token" SET "revoked_at" = :now, "updated_at" = :now
+		WHERE "user_id" = :userId AND "revoked_at" IS NULL
+		RETURNING "id"`, map[string]interface{}{
+		"userId": userID,
+		"now":    utils.Now(),
+	})
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewRefreshTokenRepository creates new refresh token repository service
+func NewRefreshTokenRepository(
+	storage data.GenericStorage,
+) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Storage: storage,
+	}
+}
@@ -0,0 +1,13 @@
+package securityevent
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the security event storage interface
+type Storage interface {
+	Insert(ctx context.Context, event *models.SecurityEvent) (*models.SecurityEvent, *types.Error)
+}
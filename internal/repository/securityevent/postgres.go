@@ -0,0 +1,33 @@
+package securityevent
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// SecurityEventRepository implements the security event storage service interface
+type SecurityEventRepository struct {
+	Storage data.GenericStorage
+}
+
+// Insert insert security event
+func (s *SecurityEventRepository) Insert(ctx context.Context, event *models.SecurityEvent) (*models.SecurityEvent, *types.Error) {
+	err := s.Storage.Insert(ctx, event)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return event, nil
+}
+
+// NewSecurityEventRepository creates new security event repository service
+func NewSecurityEventRepository(
+	storage data.GenericStorage,
+) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		Storage: storage,
+	}
+}
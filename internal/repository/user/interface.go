@@ -13,6 +13,9 @@ type Storage interface {
 	FindAll(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.User, *types.Error)
 	FindByID(ctx context.Context, userID int) (*models.User, *types.Error)
 	FindByEmail(ctx context.Context, email string) (*models.User, *types.Error)
+	FindByUsername(ctx context.Context, username string) (*models.User, *types.Error)
+	FindByClerkID(ctx context.Context, clerkID string) (*models.User, *types.Error)
+	FindByClerkIDs(ctx context.Context, clerkIDs []string) ([]*models.User, *types.Error)
 	Insert(ctx context.Context, user *models.User) (*models.User, *types.Error)
 	Update(ctx context.Context, user *models.User) (*models.User, *types.Error)
 	Delete(ctx context.Context, userID int) *types.Error
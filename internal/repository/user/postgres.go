@@ -26,7 +26,11 @@ func (s *UserRepository) FindAll(ctx context.Context, params *datatransfers.Find
 	}
 
 	if params.Phone != "" {
-		where += ` AND "phone" ILIKE :phone`
+		where += ` AND "phone" = :phone`
+	}
+
+	if params.Username != "" {
+		where += ` AND "username" ILIKE :username`
 	}
 
 	if params.UserID != 0 {
@@ -45,13 +49,14 @@ func (s *UserRepository) FindAll(ctx context.Context, params *datatransfers.Find
 	}
 
 	err := s.Storage.Where(ctx, &users, where, map[string]interface{}{
-		"userId":  params.UserID,
-		"userIds": params.UserIDs,
-		"limit":   params.Limit,
-		"email":   params.Email,
-		"phone":   params.Phone,
-		"name":    params.Name,
-		"offset":  ((params.Page - 1) * params.Limit),
+		"userId":   params.UserID,
+		"userIds":  params.UserIDs,
+		"limit":    params.Limit,
+		"email":    params.Email,
+		"phone":    params.Phone,
+		"username": params.Username,
+		"name":     params.Name,
+		"offset":   ((params.Page - 1) * params.Limit),
 	})
 	if err != nil {
 		return nil, types.NewError(err)
@@ -71,21 +76,61 @@ func (s *UserRepository) FindByID(ctx context.Context, userID int) (*models.User
 	return user, nil
 }
 
-// FindByEmail find user by its email
+// FindByEmail find user by its email, case insensitively. ILIKE would treat "_" and "%" as wildcards,
+// so the match is exact on the lowercased email.
 func (s *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, *types.Error) {
-	users, err := s.FindAll(ctx, &datatransfers.FindAllParams{
-		Email: email,
+	user := &models.User{}
+	err := s.Storage.Single(ctx, user, `"deleted_at" IS NULL AND lower("email") = lower(:email) ORDER BY "id" LIMIT 1`, map[string]interface{}{
+		"email": email,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return user, nil
+}
+
+// FindByUsername find user by its username, case insensitively and exactly like FindByEmail
+func (s *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, *types.Error) {
+	user := &models.User{}
+	err := s.Storage.Single(ctx, user, `"deleted_at" IS NULL AND lower("username") = lower(:username) ORDER BY "id" LIMIT 1`, map[string]interface{}{
+		"username": username,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return user, nil
+}
+
+// FindByClerkID find user by its Clerk ID, soft deleted users included
+func (s *UserRepository) FindByClerkID(ctx context.Context, clerkID string) (*models.User, *types.Error) {
+	user := &models.User{}
+	err := s.Storage.Single(ctx, user, `"clerk_id" = :clerkId`, map[string]interface{}{
+		"clerkId": clerkID,
 	})
 	if err != nil {
-		err.Path = ".UserRepository->FindByEmail()" + err.Path
-		return nil, err
+		return nil, types.NewError(err)
+	}
+
+	return user, nil
+}
+
+// FindByClerkIDs find users by their Clerk IDs, soft deleted users included
+func (s *UserRepository) FindByClerkIDs(ctx context.Context, clerkIDs []string) ([]*models.User, *types.Error) {
+	users := []*models.User{}
+	if len(clerkIDs) == 0 {
+		return users, nil
 	}
 
-	if len(users) < 1 || users[0].Email != email {
-		return nil, types.NewError(types.ErrNotFound)
+	err := s.Storage.Where(ctx, &users, `"clerk_id" IN (:clerkIds)`, map[string]interface{}{
+		"clerkIds": clerkIDs,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
 	}
 
-	return users[0], nil
+	return users, nil
 }
 
 // Insert insert user
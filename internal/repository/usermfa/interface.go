@@ -0,0 +1,17 @@
+package usermfa
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the user MFA storage interface
+type Storage interface {
+	FindByUserID(ctx context.Context, userID int) (*models.UserMFA, *types.Error)
+	Insert(ctx context.Context, userMFA *models.UserMFA) (*models.UserMFA, *types.Error)
+	Update(ctx context.Context, userMFA *models.UserMFA) (*models.UserMFA, *types.Error)
+	Delete(ctx context.Context, userMFAID int) *types.Error
+	MarkStepUsed(ctx context.Context, userMFAID int, step int64) (bool, *types.Error)
+}
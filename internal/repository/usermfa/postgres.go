@@ -0,0 +1,86 @@
+package usermfa
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// UserMFARepository implements the user MFA storage service interface
+type UserMFARepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByUserID find the authenticator of the user, confirmed or not
+func (s *UserMFARepository) FindByUserID(ctx context.Context, userID int) (*models.UserMFA, *types.Error) {
+	userMFA := &models.UserMFA{}
+	err := s.Storage.Single(ctx, userMFA, `"user_id" = :userId AND "deleted_at" IS NULL`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userMFA, nil
+}
+
+// Insert insert user MFA
+func (s *UserMFARepository) Insert(ctx context.Context, userMFA *models.UserMFA) (*models.UserMFA, *types.Error) {
+	err := s.Storage.Insert(ctx, userMFA)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userMFA, nil
+}
+
+// Update update user MFA
+func (s *UserMFARepository) Update(ctx context.Context, userMFA *models.UserMFA) (*models.UserMFA, *types.Error) {
+	err := s.Storage.Update(ctx, userMFA)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userMFA, nil
+}
+
+// Delete delete a user MFA
+func (s *UserMFARepository) Delete(ctx context.Context, userMFAID int) *types.Error {
+	err := s.Storage.Delete(ctx, userMFAID)
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// MarkStepUsed records the TOTP time step of an accepted code, it returns false when
+// a code of the same or a later step was already accepted so a code works only once
+func (s *UserMFARepository) MarkStepUsed(ctx context.Context, userMFAID int, step int64) (bool, *types.Error) {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "user_mfa" SET "last_used_step" = :step, "updated_at" = :now
+		WHERE "id" = :id AND "last_used_step" < :step
+		RETURNING "id"`, map[string]interface{}{
+		"id":   userMFAID,
+		"step": step,
+		"now":  utils.Now(),
+	})
+	if err != nil {
+		return false, types.NewError(err)
+	}
+
+	return len(ids) > 0, nil
+}
+
+// NewUserMFARepository creates new user MFA repository service
+func NewUserMFARepository(
+	storage data.GenericStorage,
+) *UserMFARepository {
+	return &UserMFARepository{
+		Storage: storage,
+	}
+}
@@ -0,0 +1,16 @@
+package userpassword
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the user password storage interface
+type Storage interface {
+	FindBySubject(ctx context.Context, subject string) (*models.UserPassword, *types.Error)
+	Insert(ctx context.Context, userPassword *models.UserPassword) (*models.UserPassword, *types.Error)
+	Update(ctx context.Context, userPassword *models.UserPassword) (*models.UserPassword, *types.Error)
+	Delete(ctx context.Context, userPasswordID int) *types.Error
+}
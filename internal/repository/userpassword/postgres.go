@@ -0,0 +1,66 @@
+package userpassword
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// UserPasswordRepository implements the user password storage service interface
+type UserPasswordRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindBySubject find the password of the local user with the given subject
+func (s *UserPasswordRepository) FindBySubject(ctx context.Context, subject string) (*models.UserPassword, *types.Error) {
+	userPassword := &models.UserPassword{}
+	err := s.Storage.Single(ctx, userPassword, `"subject" = :subject AND "deleted_at" IS NULL`, map[string]interface{}{
+		"subject": subject,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userPassword, nil
+}
+
+// Insert insert user password
+func (s *UserPasswordRepository) Insert(ctx context.Context, userPassword *models.UserPassword) (*models.UserPassword, *types.Error) {
+	err := s.Storage.Insert(ctx, userPassword)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userPassword, nil
+}
+
+// Update update user password
+func (s *UserPasswordRepository) Update(ctx context.Context, userPassword *models.UserPassword) (*models.UserPassword, *types.Error) {
+	err := s.Storage.Update(ctx, userPassword)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return userPassword, nil
+}
+
+// Delete delete a user password
+func (s *UserPasswordRepository) Delete(ctx context.Context, userPasswordID int) *types.Error {
+	err := s.Storage.Delete(ctx, userPasswordID)
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// NewUserPasswordRepository creates new user password repository service
+func NewUserPasswordRepository(
+	storage data.GenericStorage,
+) *UserPasswordRepository {
+	return &UserPasswordRepository{
+		Storage: storage,
+	}
+}
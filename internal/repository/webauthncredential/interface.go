@@ -0,0 +1,19 @@
+package webauthncredential
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// Storage represents the WebAuthn credential storage interface
+type Storage interface {
+	FindByID(ctx context.Context, credentialID int) (*models.WebAuthnCredential, *types.Error)
+	FindByCredentialID(ctx context.Context, webAuthnCredentialID string) (*models.WebAuthnCredential, *types.Error)
+	FindByUserID(ctx context.Context, userID int) ([]*models.WebAuthnCredential, *types.Error)
+	Insert(ctx context.Context, credential *models.WebAuthnCredential) (*models.WebAuthnCredential, *types.Error)
+	Update(ctx context.Context, credential *models.WebAuthnCredential) (*models.WebAuthnCredential, *types.Error)
+	Delete(ctx context.Context, credentialID int) *types.Error
+	UpdateSignCount(ctx context.Context, credentialID int, oldSignCount int64, newSignCount int64) (bool, *types.Error)
+}
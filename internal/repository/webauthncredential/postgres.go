@@ -0,0 +1,111 @@
+package webauthncredential
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// WebAuthnCredentialRepository implements the WebAuthn credential storage service interface
+type WebAuthnCredentialRepository struct {
+	Storage data.GenericStorage
+}
+
+// FindByID find WebAuthn credential by ID
+func (s *WebAuthnCredentialRepository) FindByID(ctx context.Context, credentialID int) (*models.WebAuthnCredential, *types.Error) {
+	credential := &models.WebAuthnCredential{}
+	err := s.Storage.FindByID(ctx, credential, credentialID)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return credential, nil
+}
+
+// FindByCredentialID find WebAuthn credential by the base64url credential ID the authenticator made up
+func (s *WebAuthnCredentialRepository) FindByCredentialID(ctx context.Context, webAuthnCredentialID string) (*models.WebAuthnCredential, *types.Error) {
+	credential := &models.WebAuthnCredential{}
+	err := s.Storage.Single(ctx, credential, `"credential_id" = :credentialId AND "deleted_at" IS NULL`, map[string]interface{}{
+		"credentialId": webAuthnCredentialID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return credential, nil
+}
+
+// FindByUserID find the WebAuthn credentials of the user, oldest first
+func (s *WebAuthnCredentialRepository) FindByUserID(ctx context.Context, userID int) ([]*models.WebAuthnCredential, *types.Error) {
+	credentials := []*models.WebAuthnCredential{}
+	err := s.Storage.Where(ctx, &credentials, `"user_id" = :userId AND "deleted_at" IS NULL ORDER BY "id"`, map[string]interface{}{
+		"userId": userID,
+	})
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return credentials, nil
+}
+
+// Insert insert WebAuthn credential
+func (s *WebAuthnCredentialRepository) Insert(ctx context.Context, credential *models.WebAuthnCredential) (*models.WebAuthnCredential, *types.Error) {
+	err := s.Storage.Insert(ctx, credential)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return credential, nil
+}
+
+// Update update WebAuthn credential
+func (s *WebAuthnCredentialRepository) Update(ctx context.Context, credential *models.WebAuthnCredential) (*models.WebAuthnCredential, *types.Error) {
+	err := s.Storage.Update(ctx, credential)
+	if err != nil {
+		return nil, types.NewError(err)
+	}
+
+	return credential, nil
+}
+
+// Delete delete a WebAuthn credential
+func (s *WebAuthnCredentialRepository) Delete(ctx context.Context, credentialID int) *types.Error {
+	err := s.Storage.Delete(ctx, credentialID)
+	if err != nil {
+		return types.NewError(err)
+	}
+
+	return nil
+}
+
+// UpdateSignCount stores the signature counter of a login, it returns false when the counter
+// changed since it was read so two logins with the same counter can't both succeed
+func (s *WebAuthnCredentialRepository) UpdateSignCount(ctx context.Context, credentialID int, oldSignCount int64, newSignCount int64) (bool, *types.Error) {
+	ids := []int{}
+	err := s.Storage.SelectWithQuery(ctx, &ids, `
+		UPDATE "webauthn_credential" SET "sign_count" = :newSignCount, "last_used_at" = :now, "updated_at" = :now
+		WHERE "id" = :id AND "sign_count" = :oldSignCount
+		RETURNING "id"`, map[string]interface{}{
+		"id":           credentialID,
+		"oldSignCount": oldSignCount,
+		"newSignCount": newSignCount,
+		"now":          utils.Now(),
+	})
+	if err != nil {
+		return false, types.NewError(err)
+	}
+
+	return len(ids) > 0, nil
+}
+
+// NewWebAuthnCredentialRepository creates new WebAuthn credential repository service
+func NewWebAuthnCredentialRepository(
+	storage data.GenericStorage,
+) *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{
+		Storage: storage,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"runtime"
+	"strings"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -21,6 +22,62 @@ var (
 	ErrLimitInput         = errors.New("name should be more than 5 char")
 	ErrNameAlreadyExist   = errors.New("name already exits")
 	ErrClerkValidationErr = errors.New("clerk validation error")
+
+	ErrPhoneAlreadyExists    = errors.New("phone already exists")
+	ErrUsernameAlreadyExists = errors.New("username already exists")
+
+	ErrTokenExpired   = errors.New("token is expired")
+	ErrTokenMalformed = errors.New("token is malformed")
+	ErrTokenInvalid   = errors.New("token is invalid")
+	ErrTokenRevoked   = errors.New("token has been revoked")
+
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	ErrRefreshTokenExpired = errors.New("refresh token is expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrUnknownIdentityProvider = errors.New("unknown identity provider")
+
+	ErrEmailNotVerified     = errors.New("email is not verified")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrTooManyRequests      = errors.New("too many requests, try again later")
+
+	ErrPhoneNotSet          = errors.New("user has no phone number")
+	ErrPhoneAlreadyVerified = errors.New("phone is already verified")
+	ErrOTPInvalid           = errors.New("verification code is invalid or expired")
+	ErrOTPTooManyAttempts   = errors.New("too many wrong verification codes, request a new one")
+
+	ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	ErrMFANotEnabled     = errors.New("MFA is not enabled")
+	ErrMFANotEnrolled    = errors.New("no pending MFA enrollment, enroll first")
+	ErrMFACodeInvalid    = errors.New("MFA code is invalid")
+	ErrMFATokenInvalid   = errors.New("MFA token is invalid or expired")
+
+	ErrPasskeyChallengeExpired  = errors.New("passkey challenge is invalid or expired, request new options")
+	ErrPasskeyInvalid           = errors.New("passkey could not be verified")
+	ErrPasskeyAlreadyRegistered = errors.New("passkey is already registered")
+
+	ErrEmailLoginInvalid = errors.New("login link or code is invalid or expired")
+
+	ErrLoginThrottled = errors.New("too many failed logins, try again later")
+	ErrForbidden      = errors.New("forbidden")
+
+	ErrSlugAlreadyExists = errors.New("slug already exists")
+	ErrInvalidClient     = errors.New("invalid client credentials")
+
+	ErrUnsupportedGrantType    = errors.New("grant type is not supported")
+	ErrUnsupportedResponseType = errors.New("response type is not supported")
+	ErrInvalidScope            = errors.New("scope is not allowed for the client")
+	ErrInsufficientScope       = errors.New("token lacks the scope the request requires")
+	ErrInvalidRedirectURI      = errors.New("redirect URI is not registered for the client")
+	ErrPKCERequired            = errors.New("a code challenge with the S256 method is required")
+	ErrInvalidGrant            = errors.New("authorization code is invalid, expired or was issued to another client")
+
+	ErrRedirectURIAlreadyRegistered = errors.New("redirect URI is already registered")
 )
 
 var (
@@ -92,3 +149,21 @@ func NewError(err error) *Error {
 		Line:     line,
 	}
 }
+
+// FieldViolation describes why the value of a single input field was rejected
+type FieldViolation struct {
+	Field   string
+	Message string
+}
+
+// FieldViolations is an error listing every rejected input field, response.Error
+// returns them as field errors
+type FieldViolations []*FieldViolation
+
+func (v FieldViolations) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, violation := range v {
+		messages = append(messages, violation.Field+" "+violation.Message)
+	}
+	return strings.Join(messages, ", ")
+}
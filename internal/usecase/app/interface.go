@@ -11,10 +11,16 @@ import (
 // ServiceInterface represents the app service interface
 type AppServiceInterface interface {
 	ListApps(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.App, int, *types.Error)
-	// GetApp(ctx context.Context, appID int) (*models.App, *types.Error)
-	// CreateApp(ctx context.Context, params *datatransfers.RegisterApp) (*models.App, *types.Error)
-	// UpdateApp(ctx context.Context, appID int, params *models.App) (*models.App, *types.Error)
-	// DeleteApp(ctx context.Context, appID int) *types.Error
+	GetApp(ctx context.Context, appID int) (*models.App, *types.Error)
+	CreateApp(ctx context.Context, params *datatransfers.AppParams) (*datatransfers.AppCredentials, *types.Error)
+	UpdateApp(ctx context.Context, appID int, params *datatransfers.AppParams) (*models.App, *types.Error)
+	DeleteApp(ctx context.Context, appID int) *types.Error
+	RotateClientSecret(ctx context.Context, appID int, adminID int, client *datatransfers.ClientInfo) (*datatransfers.AppCredentials, *types.Error)
+	GetAppByClientID(ctx context.Context, clientID string) (*models.App, *types.Error)
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.App, *types.Error)
+	ListRedirectURIs(ctx context.Context, appID int) ([]*models.AppRedirectURI, *types.Error)
+	AddRedirectURI(ctx context.Context, appID int, params *datatransfers.AppRedirectURIParams) (*models.AppRedirectURI, *types.Error)
+	RemoveRedirectURI(ctx context.Context, appID int, redirectURIID int) *types.Error
 	// ChangePassword(ctx context.Context, appID int, oldPassword, newPassword string) *types.Error
 	// Login(ctx context.Context, email string, password string) (*datatransfers.LoginResponse, *types.Error)
 }
@@ -2,30 +2,49 @@ package app
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
 	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
 	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
 	"github.com/riskibarqy/bq-account-service/internal/models"
 	"github.com/riskibarqy/bq-account-service/internal/repository/app"
+	"github.com/riskibarqy/bq-account-service/internal/repository/appredirecturi"
 	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
 	"github.com/riskibarqy/bq-account-service/utils"
 )
 
+// slugPattern is lowercase words of letters and digits joined by single dashes, e.g. "budget-buddy"
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// scopePattern is lowercase words of letters and digits joined by single colons, dots, dashes or underscores, e.g. "users:read"
+var scopePattern = regexp.MustCompile(`^[a-z0-9]+([:._-][a-z0-9]+)*$`)
+
 // Service is the domain logic implementation of app Service interface
 type AppService struct {
-	appStorage app.Storage
+	appStorage         app.Storage
+	redirectURIStorage appredirecturi.Storage
+	identityProviders  identity.Providers
+	auditService       audit.ServiceInterface
 }
 
 func (s *AppService) ListApps(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.App, int, *types.Error) {
 	// Generate cache key
 	byteParams, _ := jsoniter.Marshal(params)
-	cacheKey := fmt.Sprintf("ListApps-%s", utils.EncodeHexMD5(string(byteParams)))
+	cacheKey := constants.ListAppsCacheKeyPrefix + utils.EncodeHexMD5(string(byteParams))
 
 	// Try to get apps from Redis cache
 	cached, count, errCache := redis.GetListCache(ctx, cacheKey)
@@ -63,158 +82,439 @@ func (s *AppService) ListApps(ctx context.Context, params *datatransfers.FindAll
 	return apps, len(apps), nil
 }
 
-// // GetApp is get app
-// func (s *Service) GetApp(ctx context.Context, appID int) (*App, *types.Error) {
-// 	cacheKey := fmt.Sprintf("GetApp-%d", appID)
-
-// 	// Try to get apps from Redis cache
-// 	cached, errCache := redis.GetCache(ctx, cacheKey)
-// 	if errCache == nil && cached != "" {
-// 		// If cache hit, unmarshal the cached data into a slice of App entity
-// 		var app *App
-// 		if err := jsoniter.Unmarshal([]byte(cached), &app); err == nil {
-// 			return app, nil
-// 		}
-// 	}
+// GetApp returns a single app, deleted apps are not found
+func (s *AppService) GetApp(ctx context.Context, appID int) (*models.App, *types.Error) {
+	cacheKey := fmt.Sprintf(constants.AppCacheKey, appID)
 
-// 	app, err := s.appStorage.FindByID(ctx, appID)
-// 	if err != nil {
-// 		err.Path = ".AppService->GetApp()" + err.Path
-// 		return nil, err
-// 	}
+	// Try to get app from Redis cache
+	cached, errCache := redis.GetCache(ctx, cacheKey)
+	if errCache == nil && cached != "" {
+		// If cache hit, unmarshal the cached data into an App model
+		var app *models.App
+		if err := jsoniter.Unmarshal([]byte(cached), &app); err == nil {
+			return app, nil
+		}
+	}
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	app, err := s.appStorage.FindByID(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->GetApp()" + err.Path
+		return nil, err
+	}
+	if app.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
 
-// 		// Cache app
-// 		byteResults, _ := jsoniter.Marshal(app)
-// 		expiration := time.Duration(config.MetadataConfig.RedisExpirationShort) * time.Second
+	go func() {
+		ctxChild := context.Background()
 
-// 		if err := redis.SetCache(ctxChild, cacheKey, byteResults, expiration); err != nil {
-// 			log.Printf("Failed to set app cache: %v", err)
-// 		}
-// 	}()
+		// Cache app
+		byteResults, _ := jsoniter.Marshal(app)
+		expiration := time.Duration(config.MetadataConfig.RedisExpirationShort) * time.Second
 
-// 	return app, nil
-// }
+		if err := redis.SetCache(ctxChild, cacheKey, byteResults, expiration); err != nil {
+			log.Printf("Failed to set app cache: %v", err)
+		}
+	}()
 
-// // CreateApp create app
-// func (s *Service) CreateApp(ctx context.Context, params *App) (*App, *types.Error) {
-// 	apps, _, errType := s.ListApps(ctx, &datatransfers.FindAllParams{
-// 		Email: params.Email,
-// 	})
-// 	if errType != nil {
-// 		errType.Path = ".AppService->CreateApp()" + errType.Path
-// 		return nil, errType
-// 	}
-// 	if len(apps) > 0 {
-// 		return nil, &types.Error{
-// 			Path:    ".AppService->CreateApp()",
-// 			Message: ErrEmailAlreadyExists.Error(),
-// 			Error:   ErrEmailAlreadyExists,
-// 			Type:    "validation-error",
-// 		}
-// 	}
+	return app, nil
+}
 
-// 	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
-// 	if err != nil {
-// 		return nil, &types.Error{
-// 			Path:    ".AppService->CreateApp()",
-// 			Message: err.Error(),
-// 			Error:   err,
-// 			Type:    "golang-error",
-// 		}
-// 	}
+// CreateApp creates an app with a new client id and secret, users registering through it are created in its
+// identity provider, IDENTITY_PROVIDER when none is given. The plain secret is only part of this response.
+func (s *AppService) CreateApp(ctx context.Context, params *datatransfers.AppParams) (*datatransfers.AppCredentials, *types.Error) {
+	if params.IdentityProvider == "" {
+		params.IdentityProvider = config.AppConfig.IdentityProvider
+	}
 
-// 	now := utils.Now()
+	err := s.validateApp(ctx, 0, params)
+	if err != nil {
+		err.Path = ".AppService->CreateApp()" + err.Path
+		return nil, err
+	}
 
-// 	app := &App{
-// 		Name:     params.Name,
-// 		Email:    params.Email,
-// 		Password: string(bcryptHash),
-// 		// Token:          nil,
-// 		// TokenExpiredAt: nil,
-// 		CreatedAt: now,
-// 		UpdatedAt: &now,
-// 	}
+	clientID, errToken := utils.GenerateRandomToken(24)
+	if errToken != nil {
+		return nil, appError(".AppService->CreateApp()", errToken)
+	}
+	clientSecret, errToken := utils.GenerateRandomToken(32)
+	if errToken != nil {
+		return nil, appError(".AppService->CreateApp()", errToken)
+	}
 
-// 	app, errType = s.appStorage.Insert(ctx, app)
-// 	if errType != nil {
-// 		errType.Path = ".AppService->CreateApp()" + errType.Path
-// 		return nil, errType
-// 	}
+	now := utils.Now()
+	app, err := s.appStorage.Insert(ctx, &models.App{
+		Name:             params.Name,
+		Slug:             params.Slug,
+		ClientID:         clientID,
+		ClientSecretHash: utils.HashToken(clientSecret),
+		IdentityProvider: params.IdentityProvider,
+		AllowedScopes:    uniqueScopes(params.AllowedScopes),
+		CreatedAt:        now,
+		UpdatedAt:        &now,
+	})
+	if err != nil {
+		err.Path = ".AppService->CreateApp()" + err.Path
+		return nil, err
+	}
 
-// 	return app, nil
-// }
+	s.invalidateAppCache(app.ID)
 
-// // UpdateApp update a app
-// func (s *Service) UpdateApp(ctx context.Context, appID int, params *App) (*App, *types.Error) {
-// 	app, err := s.GetApp(ctx, appID)
-// 	if err != nil {
-// 		err.Path = ".AppService->UpdateApp()" + err.Path
-// 		return nil, err
-// 	}
+	return &datatransfers.AppCredentials{
+		App:          app,
+		ClientSecret: clientSecret,
+	}, nil
+}
 
-// 	apps, _, err := s.ListApps(ctx, &datatransfers.FindAllParams{
-// 		Email: params.Email,
-// 	})
-// 	if err != nil {
-// 		err.Path = ".AppService->UpdateApp()" + err.Path
-// 		return nil, err
-// 	}
-// 	if len(apps) > 0 {
-// 		return nil, &types.Error{
-// 			Path:    ".AppService->CreateApp()",
-// 			Message: data.ErrAlreadyExist.Error(),
-// 			Error:   data.ErrAlreadyExist,
-// 			Type:    "validation-error",
-// 		}
-// 	}
+// RotateClientSecret issues a new client secret for an app. The current secret keeps working for
+// APP_SECRET_GRACE_PERIOD seconds, a secret replaced before that stops working right away.
+// The rotation is recorded as a security event of the admin doing it.
+func (s *AppService) RotateClientSecret(ctx context.Context, appID int, adminID int, client *datatransfers.ClientInfo) (*datatransfers.AppCredentials, *types.Error) {
+	app, err := s.appStorage.FindByID(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->RotateClientSecret()" + err.Path
+		return nil, err
+	}
+	if app.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
 
-// 	app.Name = params.Name
-// 	app.Email = params.Email
+	clientSecret, errToken := utils.GenerateRandomToken(32)
+	if errToken != nil {
+		return nil, appError(".AppService->RotateClientSecret()", errToken)
+	}
 
-// 	app, err = s.appStorage.Update(ctx, app)
-// 	if err != nil {
-// 		err.Path = ".AppService->UpdateApp()" + err.Path
-// 		return nil, err
-// 	}
+	now := utils.Now()
+	app.PreviousClientSecretHash = nil
+	app.PreviousClientSecretExpiresAt = nil
+	if config.AppConfig.AppSecretGracePeriod > 0 && app.ClientSecretHash != "" {
+		previousHash := app.ClientSecretHash
+		expiresAt := now + config.AppConfig.AppSecretGracePeriod
+		app.PreviousClientSecretHash = &previousHash
+		app.PreviousClientSecretExpiresAt = &expiresAt
+	}
+	app.ClientSecretHash = utils.HashToken(clientSecret)
+	app.UpdatedAt = &now
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	app, err = s.appStorage.Update(ctx, app)
+	if err != nil {
+		err.Path = ".AppService->RotateClientSecret()" + err.Path
+		return nil, err
+	}
 
-// 		cacheKey := fmt.Sprintf("GetApp-%d", appID)
+	metadata := types.Metadata{
+		"appId":    app.ID,
+		"clientId": app.ClientID,
+	}
+	if app.PreviousClientSecretExpiresAt != nil {
+		metadata["previousSecretExpiresAt"] = *app.PreviousClientSecretExpiresAt
+	}
+	err = s.auditService.Record(ctx, audit.EventClientSecretRotated, &adminID, client, metadata)
+	if err != nil {
+		err.Path = ".AppService->RotateClientSecret()" + err.Path
+		return nil, err
+	}
 
-// 		// delete app cache
-// 		if err := redis.DeleteCache(ctxChild, cacheKey); err != nil {
-// 			log.Printf("Failed to set app cache: %v", err)
-// 		}
-// 	}()
+	s.invalidateAppCache(appID)
 
-// 	return app, nil
-// }
+	return &datatransfers.AppCredentials{
+		App:          app,
+		ClientSecret: clientSecret,
+	}, nil
+}
 
-// // DeleteApp delete a app
-// func (s *Service) DeleteApp(ctx context.Context, appID int) *types.Error {
-// 	err := s.appStorage.Delete(ctx, appID)
-// 	if err != nil {
-// 		err.Path = ".AppService->DeleteApp()" + err.Path
-// 		return err
-// 	}
+// GetAppByClientID returns the app with the client id, deleted apps are not found
+func (s *AppService) GetAppByClientID(ctx context.Context, clientID string) (*models.App, *types.Error) {
+	app, err := s.appStorage.FindByClientID(ctx, clientID)
+	if err != nil {
+		err.Path = ".AppService->GetAppByClientID()" + err.Path
+		return nil, err
+	}
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	return app, nil
+}
 
-// 		cacheKey := fmt.Sprintf("GetApp-%d", appID)
+// AuthenticateClient returns the app with the client id when the secret is its current one, or its previous one
+// still within the grace period. Hashes are read from the database, they are never cached.
+func (s *AppService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*models.App, *types.Error) {
+	app, err := s.appStorage.FindByClientID(ctx, clientID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, appError(".AppService->AuthenticateClient()", types.ErrInvalidClient)
+		}
+		err.Path = ".AppService->AuthenticateClient()" + err.Path
+		return nil, err
+	}
 
-// 		// delete app cache
-// 		if err := redis.DeleteCache(ctxChild, cacheKey); err != nil {
-// 			log.Printf("Failed to set app cache: %v", err)
-// 		}
-// 	}()
+	secretHash := []byte(utils.HashToken(clientSecret))
+	if app.ClientSecretHash != "" && subtle.ConstantTimeCompare([]byte(app.ClientSecretHash), secretHash) == 1 {
+		return app, nil
+	}
+	if app.PreviousClientSecretHash != nil && app.PreviousClientSecretExpiresAt != nil &&
+		*app.PreviousClientSecretExpiresAt > utils.Now() &&
+		subtle.ConstantTimeCompare([]byte(*app.PreviousClientSecretHash), secretHash) == 1 {
+		return app, nil
+	}
 
-// 	return nil
-// }
+	return nil, appError(".AppService->AuthenticateClient()", types.ErrInvalidClient)
+}
+
+// UpdateApp updates the name, slug, identity provider and allowed scopes of an app, an empty identity provider
+// or no scopes keep the current ones
+func (s *AppService) UpdateApp(ctx context.Context, appID int, params *datatransfers.AppParams) (*models.App, *types.Error) {
+	app, err := s.appStorage.FindByID(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->UpdateApp()" + err.Path
+		return nil, err
+	}
+	if app.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
+
+	if params.IdentityProvider == "" {
+		params.IdentityProvider = app.IdentityProvider
+	}
+
+	err = s.validateApp(ctx, appID, params)
+	if err != nil {
+		err.Path = ".AppService->UpdateApp()" + err.Path
+		return nil, err
+	}
+
+	now := utils.Now()
+	app.Name = params.Name
+	app.Slug = params.Slug
+	app.IdentityProvider = params.IdentityProvider
+	if params.AllowedScopes != nil {
+		app.AllowedScopes = uniqueScopes(params.AllowedScopes)
+	}
+	app.UpdatedAt = &now
+
+	app, err = s.appStorage.Update(ctx, app)
+	if err != nil {
+		err.Path = ".AppService->UpdateApp()" + err.Path
+		return nil, err
+	}
+
+	s.invalidateAppCache(appID)
+
+	return app, nil
+}
+
+// DeleteApp soft deletes an app, its slug stays taken
+func (s *AppService) DeleteApp(ctx context.Context, appID int) *types.Error {
+	app, err := s.appStorage.FindByID(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->DeleteApp()" + err.Path
+		return err
+	}
+	if app.DeletedAt != nil {
+		return types.NewError(data.ErrNotFound)
+	}
+
+	err = s.appStorage.Delete(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->DeleteApp()" + err.Path
+		return err
+	}
+
+	s.invalidateAppCache(appID)
+
+	return nil
+}
+
+// ListRedirectURIs lists the redirect URIs registered for an app
+func (s *AppService) ListRedirectURIs(ctx context.Context, appID int) ([]*models.AppRedirectURI, *types.Error) {
+	_, err := s.GetApp(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->ListRedirectURIs()" + err.Path
+		return nil, err
+	}
+
+	redirectURIs, err := s.redirectURIStorage.FindByAppID(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->ListRedirectURIs()" + err.Path
+		return nil, err
+	}
+
+	return redirectURIs, nil
+}
+
+// AddRedirectURI registers a redirect URI for an app. Authorization requests must name it exactly, so it is
+// stored as given.
+func (s *AppService) AddRedirectURI(ctx context.Context, appID int, params *datatransfers.AppRedirectURIParams) (*models.AppRedirectURI, *types.Error) {
+	_, err := s.GetApp(ctx, appID)
+	if err != nil {
+		err.Path = ".AppService->AddRedirectURI()" + err.Path
+		return nil, err
+	}
+
+	if message := validateRedirectURI(params.RedirectURI); message != "" {
+		violations := types.FieldViolations{{Field: "redirectUri", Message: message}}
+		return nil, &types.Error{
+			Path:    ".AppService->AddRedirectURI()",
+			Message: violations.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	existing, err := s.redirectURIStorage.FindByAppIDAndURI(ctx, appID, params.RedirectURI)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".AppService->AddRedirectURI()" + err.Path
+		return nil, err
+	}
+	if existing != nil {
+		return nil, appError(".AppService->AddRedirectURI()", types.ErrRedirectURIAlreadyRegistered)
+	}
+
+	now := utils.Now()
+	redirectURI, err := s.redirectURIStorage.Insert(ctx, &models.AppRedirectURI{
+		AppID:       appID,
+		RedirectURI: params.RedirectURI,
+		CreatedAt:   now,
+		UpdatedAt:   &now,
+	})
+	if err != nil {
+		err.Path = ".AppService->AddRedirectURI()" + err.Path
+		return nil, err
+	}
+
+	return redirectURI, nil
+}
+
+// RemoveRedirectURI removes a redirect URI of an app, codes already issued for it still have to be exchanged with it
+func (s *AppService) RemoveRedirectURI(ctx context.Context, appID int, redirectURIID int) *types.Error {
+	redirectURI, err := s.redirectURIStorage.FindByID(ctx, redirectURIID)
+	if err != nil {
+		err.Path = ".AppService->RemoveRedirectURI()" + err.Path
+		return err
+	}
+	if redirectURI.AppID != appID || redirectURI.DeletedAt != nil {
+		return types.NewError(data.ErrNotFound)
+	}
+
+	err = s.redirectURIStorage.Delete(ctx, redirectURIID)
+	if err != nil {
+		err.Path = ".AppService->RemoveRedirectURI()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// validateRedirectURI returns why a redirect URI can't be registered, or an empty string when it can. Web apps use
+// https, http is only allowed on loopback for development, and native apps use a reverse domain scheme as in RFC 8252.
+func validateRedirectURI(redirectURI string) string {
+	parsed, errParse := url.Parse(redirectURI)
+	if errParse != nil || parsed.Scheme == "" {
+		return "must be an absolute URI"
+	}
+	if parsed.Fragment != "" || strings.Contains(redirectURI, "#") {
+		return "must not have a fragment"
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		if parsed.Host == "" {
+			return "must have a host"
+		}
+	case "http":
+		host := parsed.Hostname()
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "must use https unless it is on loopback"
+		}
+	default:
+		if !strings.Contains(parsed.Scheme, ".") {
+			return "must use https or a reverse domain scheme such as com.example.app"
+		}
+	}
+
+	return ""
+}
+
+// validateApp checks the slug format, the identity provider and the scopes, then makes sure no other app has the slug.
+// The uniqueness check reads the database directly, a stale cache must not let a duplicate through.
+func (s *AppService) validateApp(ctx context.Context, appID int, params *datatransfers.AppParams) *types.Error {
+	var violations types.FieldViolations
+	if len(params.Slug) > 50 || !slugPattern.MatchString(params.Slug) {
+		violations = append(violations, &types.FieldViolation{Field: "slug", Message: "must be up to 50 lowercase letters and digits joined by single dashes"})
+	}
+	if _, err := s.identityProviders.Get(params.IdentityProvider); err != nil {
+		violations = append(violations, &types.FieldViolation{Field: "identityProvider", Message: "must be a configured identity provider"})
+	}
+	for _, scope := range params.AllowedScopes {
+		if len(scope) > 100 || !scopePattern.MatchString(scope) {
+			violations = append(violations, &types.FieldViolation{Field: "allowedScopes", Message: "must be up to 100 lowercase letters and digits joined by single colons, dots, dashes or underscores"})
+			break
+		}
+	}
+	if len(violations) > 0 {
+		return &types.Error{
+			Path:    ".AppService->validateApp()",
+			Message: violations.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	existing, err := s.appStorage.FindBySlug(ctx, params.Slug)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".AppService->validateApp()" + err.Path
+		return err
+	}
+	if existing != nil && existing.ID != appID {
+		return &types.Error{
+			Path:    ".AppService->validateApp()",
+			Message: types.ErrSlugAlreadyExists.Error(),
+			Error:   types.ErrSlugAlreadyExists,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return nil
+}
+
+// uniqueScopes drops repeated scopes, keeping their order
+func uniqueScopes(scopes []string) types.StringArray {
+	unique := types.StringArray{}
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+// appError wraps an error of the app service
+func appError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// invalidateAppCache drops the cached app and every cached app list
+func (s *AppService) invalidateAppCache(appID int) {
+	go func() {
+		ctxChild := context.Background()
+
+		// delete app cache
+		if err := redis.DeleteCache(ctxChild, fmt.Sprintf(constants.AppCacheKey, appID)); err != nil {
+			log.Printf("Failed to delete app cache: %v", err)
+		}
+
+		for _, prefix := range []string{constants.ListAppsCacheKeyPrefix, "cnt-" + constants.ListAppsCacheKeyPrefix} {
+			if err := redis.DeleteCacheByPrefix(ctxChild, prefix); err != nil {
+				log.Printf("Failed to delete app list cache: %v", err)
+			}
+		}
+	}()
+}
 
 // // ChangePassword change password
 // func (s *Service) ChangePassword(ctx context.Context, appID int, oldPassword, newPassword string) *types.Error {
@@ -326,8 +626,14 @@ func (s *AppService) ListApps(ctx context.Context, params *datatransfers.FindAll
 // NewService creates a new app AppService
 func NewService(
 	appStorage app.Storage,
+	redirectURIStorage appredirecturi.Storage,
+	identityProviders identity.Providers,
+	auditService audit.ServiceInterface,
 ) *AppService {
 	return &AppService{
-		appStorage: appStorage,
+		appStorage:         appStorage,
+		redirectURIStorage: redirectURIStorage,
+		identityProviders:  identityProviders,
+		auditService:       auditService,
 	}
 }
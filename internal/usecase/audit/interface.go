@@ -0,0 +1,14 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the audit service interface
+type ServiceInterface interface {
+	Record(ctx context.Context, eventType string, userID *int, client *datatransfers.ClientInfo, metadata types.Metadata) *types.Error
+	RecordDetached(ctx context.Context, eventType string, userID *int, client *datatransfers.ClientInfo, metadata types.Metadata) *types.Error
+}
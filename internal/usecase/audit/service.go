@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/securityevent"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Security event types
+const (
+	EventPasswordReset               = "password_reset"
+	EventMFAEnabled                  = "mfa_enabled"
+	EventMFADisabled                 = "mfa_disabled"
+	EventMFARecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+	EventMFARecoveryCodeUsed         = "mfa_recovery_code_used"
+	EventPasskeyAdded                = "passkey_added"
+	EventPasskeyRemoved              = "passkey_removed"
+	EventPasskeyCloneDetected        = "passkey_clone_detected"
+	EventAccountLocked               = "account_locked"
+	EventAccountUnlocked             = "account_unlocked"
+	EventIPLocked                    = "ip_locked"
+	EventClientSecretRotated         = "client_secret_rotated"
+	EventOAuthConsentGranted         = "oauth_consent_granted"
+	EventOAuthConsentRevoked         = "oauth_consent_revoked"
+)
+
+// Service is the domain logic implementation of audit Service interface
+type Service struct {
+	securityEventStorage securityevent.Storage
+}
+
+// Record stores a security event, userID is nil when the event isn't tied to a known account
+func (s *Service) Record(ctx context.Context, eventType string, userID *int, client *datatransfers.ClientInfo, metadata types.Metadata) *types.Error {
+	event := &models.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Metadata:  metadata,
+		CreatedAt: utils.Now(),
+	}
+	if event.Metadata == nil {
+		event.Metadata = types.Metadata{}
+	}
+	if client != nil {
+		event.IP = client.IP
+		event.UserAgent = client.UserAgent
+	}
+
+	_, err := s.securityEventStorage.Insert(ctx, event)
+	if err != nil {
+		err.Path = ".AuditService->Record()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// RecordDetached stores a security event outside the transaction of ctx, for events about
+// requests that fail, whose transaction is rolled back
+func (s *Service) RecordDetached(ctx context.Context, eventType string, userID *int, client *datatransfers.ClientInfo, metadata types.Metadata) *types.Error {
+	err := s.Record(data.WithoutTransaction(ctx), eventType, userID, client, metadata)
+	if err != nil {
+		err.Path = ".AuditService->RecordDetached()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// NewAuditService creates a new audit Service
+func NewAuditService(
+	securityEventStorage securityevent.Storage,
+) *Service {
+	return &Service{
+		securityEventStorage: securityEventStorage,
+	}
+}
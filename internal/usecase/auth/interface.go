@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
+)
+
+// ServiceInterface represents the auth service interface
+type ServiceInterface interface {
+	Login(ctx context.Context, params *datatransfers.LoginParams, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error)
+	ChangePassword(ctx context.Context, userID int, params *datatransfers.ChangePasswordParams, client *datatransfers.ClientInfo) *types.Error
+	LoginMFA(ctx context.Context, params *datatransfers.MFALoginParams, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error)
+	LoginPasskey(ctx context.Context, response *webauthn.AssertionResponse, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error)
+	RequestEmailLogin(ctx context.Context, email string, nonce string) *types.Error
+	VerifyEmailLogin(ctx context.Context, params *datatransfers.EmailLoginVerifyParams, nonce string, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error)
+	ForgotPassword(ctx context.Context, email string) *types.Error
+	ResetPassword(ctx context.Context, params *datatransfers.ResetPasswordParams, client *datatransfers.ClientInfo) *types.Error
+}
@@ -0,0 +1,679 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/mailer"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/password"
+	"github.com/riskibarqy/bq-account-service/internal/repository/passwordresettoken"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/lockout"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/mfa"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/passkey"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/token"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Service is the domain logic implementation of auth Service interface
+type Service struct {
+	userStorage       user.Storage
+	identityProviders identity.Providers
+	sessionService    session.ServiceInterface
+	tokenService      token.ServiceInterface
+	passwordPolicy    *password.Policy
+
+	passwordResetTokenStorage passwordresettoken.Storage
+	mailSender                mailer.Sender
+	auditService              audit.ServiceInterface
+	mfaService                mfa.ServiceInterface
+	passkeyService            passkey.ServiceInterface
+	lockoutService            lockout.ServiceInterface
+}
+
+// emailLogin is a pending email login of a user, as cached. The link token and the code are
+// both hashed with the nonce of the browser that asked for them.
+type emailLogin struct {
+	UserID    int    `json:"userId"`
+	TokenHash string `json:"tokenHash"`
+	CodeHash  string `json:"codeHash"`
+}
+
+// emailLoginCodeDigits is the length of email login codes
+const emailLoginCodeDigits = 6
+
+// Login checks the credentials against the identity provider of the user, then starts a session and issues its tokens.
+// Failed logins are throttled by account and IP, a throttled login gets types.ErrLoginThrottled without its
+// password being checked.
+func (s *Service) Login(ctx context.Context, params *datatransfers.LoginParams, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	err := s.lockoutService.Check(ctx, params.Email, client.IP)
+	if err != nil {
+		err.Path = ".AuthService->Login()" + err.Path
+		return nil, err
+	}
+
+	currentUser, err := s.userStorage.FindByEmail(ctx, params.Email)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".AuthService->Login()" + err.Path
+		return nil, err
+	}
+
+	// Unknown emails still go through a provider, so neither the response nor its timing tells whether the account exists
+	providerName := config.AppConfig.IdentityProvider
+	if currentUser != nil {
+		providerName = currentUser.IdentityProvider
+	}
+	provider, err := s.identityProviders.Get(providerName)
+	if err != nil {
+		err.Path = ".AuthService->Login()" + err.Path
+		return nil, err
+	}
+
+	remote, err := provider.VerifyCredentials(ctx, params.Email, params.Password)
+	if err != nil && err.Error != types.ErrInvalidCredentials {
+		err.Path = ".AuthService->Login()" + err.Path
+		return nil, err
+	}
+	if err != nil || currentUser == nil || remote.ID != currentUser.ClerkID || !currentUser.IsActive {
+		var userID *int
+		if currentUser != nil {
+			userID = &currentUser.ID
+		}
+		err = s.lockoutService.RecordFailure(ctx, params.Email, userID, client)
+		if err != nil {
+			err.Path = ".AuthService->Login()" + err.Path
+			return nil, err
+		}
+		return nil, invalidCredentials(".AuthService->Login()")
+	}
+
+	result, err := s.completeLogin(ctx, currentUser, []string{session.AuthMethodPassword}, client)
+	if err != nil {
+		err.Path = ".AuthService->Login()" + err.Path
+		return nil, err
+	}
+
+	// With MFA pending the failures stay counted, the password alone mustn't reset the guesses at the code
+	if !result.MFARequired {
+		err = s.lockoutService.RecordSuccess(ctx, params.Email)
+		if err != nil {
+			err.Path = ".AuthService->Login()" + err.Path
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// RequestEmailLogin mails a login link and code to the user, at most once per EMAIL_LOGIN_RESEND_INTERVAL for
+// each browser and EMAIL_LOGIN_USER_LIMIT times per EMAIL_LOGIN_USER_WINDOW in all, so somebody else asking for
+// emails can't use up the interval of the user's own browser. Both only work from the browser holding nonce, and a new email replaces the pending one of that browser.
+// Unknown and inactive accounts get no email and no error, so the caller can't tell them apart.
+func (s *Service) RequestEmailLogin(ctx context.Context, email string, nonce string) *types.Error {
+	currentUser, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".AuthService->RequestEmailLogin()" + err.Path
+		return err
+	}
+	if currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil
+	}
+
+	nonceHash := utils.HashToken(nonce)
+	interval := time.Duration(config.AppConfig.EmailLoginResendInterval) * time.Second
+	allowed, errCache := redis.SetCacheIfNotExists(ctx, fmt.Sprintf(constants.EmailLoginResendCacheKey, currentUser.ID, nonceHash), 1, interval)
+	if errCache != nil {
+		return authError(".AuthService->RequestEmailLogin()", errCache)
+	}
+	if !allowed {
+		return nil
+	}
+
+	window := time.Duration(config.AppConfig.EmailLoginUserWindow) * time.Second
+	sent, errCache := redis.Increment(ctx, fmt.Sprintf(constants.EmailLoginUserCacheKey, currentUser.ID), window)
+	if errCache != nil {
+		return authError(".AuthService->RequestEmailLogin()", errCache)
+	}
+	if sent > int64(config.AppConfig.EmailLoginUserLimit) {
+		return nil
+	}
+
+	loginToken, errToken := utils.GenerateRandomToken(32)
+	if errToken != nil {
+		return authError(".AuthService->RequestEmailLogin()", errToken)
+	}
+	code, errCode := utils.GenerateNumericCode(emailLoginCodeDigits)
+	if errCode != nil {
+		return authError(".AuthService->RequestEmailLogin()", errCode)
+	}
+
+	cached, errMarshal := jsoniter.Marshal(&emailLogin{
+		UserID:    currentUser.ID,
+		TokenHash: hashEmailLoginSecret(nonce, loginToken),
+		CodeHash:  hashEmailLoginSecret(nonce, code),
+	})
+	if errMarshal != nil {
+		return authError(".AuthService->RequestEmailLogin()", errMarshal)
+	}
+
+	ttl := time.Duration(config.AppConfig.EmailLoginTTL) * time.Second
+	if errCache := redis.SetCache(ctx, fmt.Sprintf(constants.EmailLoginCacheKey, nonceHash), cached, ttl); errCache != nil {
+		return authError(".AuthService->RequestEmailLogin()", errCache)
+	}
+	if errCache := redis.DeleteCache(ctx, fmt.Sprintf(constants.EmailLoginAttemptsCacheKey, nonceHash)); errCache != nil {
+		return authError(".AuthService->RequestEmailLogin()", errCache)
+	}
+
+	link, errURL := url.Parse(config.AppConfig.EmailLoginURL)
+	if errURL != nil {
+		return authError(".AuthService->RequestEmailLogin()", errURL)
+	}
+	query := link.Query()
+	query.Set("token", loginToken)
+	link.RawQuery = query.Encode()
+
+	errSend := s.mailSender.Send(ctx, &mailer.Message{
+		To:      currentUser.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to log in, or enter the code %s. They expire in %s, work once and only "+
+			"in the browser you asked from.\n\n%s\n\nIf you didn't try to log in you can ignore this email.\n",
+			currentUser.Name, code, ttl, link.String()),
+	})
+	if errSend != nil {
+		return authError(".AuthService->RequestEmailLogin()", errSend)
+	}
+
+	return nil
+}
+
+// VerifyEmailLogin logs in with the link token or code of the pending email login of the browser holding
+// nonce. The pending login works once and is dropped after EMAIL_LOGIN_MAX_ATTEMPTS wrong tries.
+func (s *Service) VerifyEmailLogin(ctx context.Context, params *datatransfers.EmailLoginVerifyParams, nonce string, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	if nonce == "" {
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrEmailLoginInvalid)
+	}
+	nonceHash := utils.HashToken(nonce)
+	loginKey := fmt.Sprintf(constants.EmailLoginCacheKey, nonceHash)
+
+	cached, errCache := redis.GetCache(ctx, loginKey)
+	if errCache != nil {
+		return nil, authError(".AuthService->VerifyEmailLogin()", errCache)
+	}
+	if cached == "" {
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrEmailLoginInvalid)
+	}
+
+	pending := &emailLogin{}
+	if errUnmarshal := jsoniter.UnmarshalFromString(cached, pending); errUnmarshal != nil {
+		return nil, authError(".AuthService->VerifyEmailLogin()", errUnmarshal)
+	}
+
+	// Counting before comparing keeps concurrent guesses from getting past the limit
+	ttl := time.Duration(config.AppConfig.EmailLoginTTL) * time.Second
+	attempts, errCache := redis.Increment(ctx, fmt.Sprintf(constants.EmailLoginAttemptsCacheKey, nonceHash), ttl)
+	if errCache != nil {
+		return nil, authError(".AuthService->VerifyEmailLogin()", errCache)
+	}
+	if attempts > int64(config.AppConfig.EmailLoginMaxAttempts) {
+		s.dropEmailLogin(ctx, nonceHash)
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrOTPTooManyAttempts)
+	}
+
+	secret, expectedHash := params.Code, pending.CodeHash
+	if params.Token != "" {
+		secret, expectedHash = params.Token, pending.TokenHash
+	}
+	if subtle.ConstantTimeCompare([]byte(hashEmailLoginSecret(nonce, secret)), []byte(expectedHash)) != 1 {
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrEmailLoginInvalid)
+	}
+
+	// Of concurrent logins with the right secret only the one that takes the pending login goes through
+	taken, errCache := redis.TakeCache(ctx, loginKey)
+	if errCache != nil {
+		return nil, authError(".AuthService->VerifyEmailLogin()", errCache)
+	}
+	if taken == "" {
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrEmailLoginInvalid)
+	}
+	s.dropEmailLogin(ctx, nonceHash)
+
+	currentUser, err := s.userStorage.FindByID(ctx, pending.UserID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".AuthService->VerifyEmailLogin()" + err.Path
+		return nil, err
+	}
+	if currentUser == nil || currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil, authError(".AuthService->VerifyEmailLogin()", types.ErrEmailLoginInvalid)
+	}
+
+	// The link and the code are both one-time secrets sent to the email address
+	result, err := s.completeLogin(ctx, currentUser, []string{session.AuthMethodOTP}, client)
+	if err != nil {
+		err.Path = ".AuthService->VerifyEmailLogin()" + err.Path
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoginMFA finishes a login of a user with MFA enabled, exchanging the MFA pending token from Login and
+// a TOTP or recovery code for a session. A token takes MFA_MAX_ATTEMPTS wrong codes and is single use.
+func (s *Service) LoginMFA(ctx context.Context, params *datatransfers.MFALoginParams, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	userID, tokenID, authMethods, errToken := config.ParseMFAToken(params.MFAToken)
+	if errToken != nil {
+		return nil, authError(".AuthService->LoginMFA()", types.ErrMFATokenInvalid)
+	}
+
+	ttl := time.Duration(config.AppConfig.MFATokenTTL) * time.Second
+	attempts, errCache := redis.Increment(ctx, fmt.Sprintf(constants.MFAAttemptsCacheKey, tokenID), ttl)
+	if errCache != nil {
+		return nil, authError(".AuthService->LoginMFA()", errCache)
+	}
+	if attempts > int64(config.AppConfig.MFAMaxAttempts) {
+		return nil, authError(".AuthService->LoginMFA()", types.ErrOTPTooManyAttempts)
+	}
+
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".AuthService->LoginMFA()" + err.Path
+		return nil, err
+	}
+	if currentUser == nil || currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil, authError(".AuthService->LoginMFA()", types.ErrMFATokenInvalid)
+	}
+
+	err = s.mfaService.Verify(ctx, currentUser.ID, params.Code, client)
+	if err != nil {
+		err.Path = ".AuthService->LoginMFA()" + err.Path
+		return nil, err
+	}
+
+	unused, errCache := redis.SetCacheIfNotExists(ctx, fmt.Sprintf(constants.MFATokenUsedCacheKey, tokenID), 1, ttl)
+	if errCache != nil {
+		return nil, authError(".AuthService->LoginMFA()", errCache)
+	}
+	if !unused {
+		return nil, authError(".AuthService->LoginMFA()", types.ErrMFATokenInvalid)
+	}
+
+	// A TOTP and a recovery code are both one-time passwords
+	authMethods = addAuthMethod(authMethods, session.AuthMethodOTP)
+	return s.issueLogin(ctx, currentUser, addAuthMethod(authMethods, session.AuthMethodMFA), client)
+}
+
+// LoginPasskey signs in with the response to passkey login options. Passkeys verify the user on the
+// authenticator, so they stand in for both factors and TOTP isn't asked.
+func (s *Service) LoginPasskey(ctx context.Context, response *webauthn.AssertionResponse, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	currentUser, err := s.passkeyService.VerifyLogin(ctx, response, client)
+	if err != nil {
+		err.Path = ".AuthService->LoginPasskey()" + err.Path
+		return nil, err
+	}
+
+	return s.issueLogin(ctx, currentUser, []string{session.AuthMethodHardwareKey, session.AuthMethodMFA}, client)
+}
+
+// ChangePassword replaces the password of the user after checking the old one. Wrong old passwords count
+// as failed logins, so a stolen access token doesn't get unlimited guesses.
+func (s *Service) ChangePassword(ctx context.Context, userID int, params *datatransfers.ChangePasswordParams, client *datatransfers.ClientInfo) *types.Error {
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+	if currentUser.DeletedAt != nil {
+		return types.NewError(data.ErrNotFound)
+	}
+
+	violations := s.passwordPolicy.Check("newPassword", params.NewPassword, &password.PolicyUser{
+		Name:     currentUser.Name,
+		Email:    currentUser.Email,
+		Username: currentUser.Username,
+	})
+	if violations != nil {
+		return &types.Error{
+			Path:    ".AuthService->ChangePassword()",
+			Message: violations.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	err = s.lockoutService.Check(ctx, currentUser.Email, client.IP)
+	if err != nil {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+
+	provider, err := s.identityProviders.Get(currentUser.IdentityProvider)
+	if err != nil {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+
+	remote, err := provider.VerifyCredentials(ctx, currentUser.Email, params.OldPassword)
+	if err != nil && err.Error != types.ErrInvalidCredentials {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+	if err != nil || remote.ID != currentUser.ClerkID {
+		err = s.lockoutService.RecordFailure(ctx, currentUser.Email, &currentUser.ID, client)
+		if err != nil {
+			err.Path = ".AuthService->ChangePassword()" + err.Path
+			return err
+		}
+		return &types.Error{
+			Path:    ".AuthService->ChangePassword()",
+			Message: types.ErrWrongPassword.Error(),
+			Error:   types.ErrWrongPassword,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	err = s.lockoutService.RecordSuccess(ctx, currentUser.Email)
+	if err != nil {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+
+	err = provider.SetPassword(ctx, currentUser.ClerkID, params.NewPassword)
+	if err != nil {
+		err.Path = ".AuthService->ChangePassword()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// ForgotPassword mails a password reset link to the active user with the given email, at most once
+// per PASSWORD_RESET_RESEND_INTERVAL. Unknown emails and throttled requests are silently ignored
+// so the caller can't tell whether the account exists.
+func (s *Service) ForgotPassword(ctx context.Context, email string) *types.Error {
+	currentUser, err := s.userStorage.FindByEmail(ctx, email)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".AuthService->ForgotPassword()" + err.Path
+		return err
+	}
+	if currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil
+	}
+
+	interval := time.Duration(config.AppConfig.PasswordResetResendInterval) * time.Second
+	allowed, errCache := redis.SetCacheIfNotExists(ctx, fmt.Sprintf(constants.PasswordResetCacheKey, currentUser.ID), 1, interval)
+	if errCache != nil {
+		return authError(".AuthService->ForgotPassword()", errCache)
+	}
+	if !allowed {
+		return nil
+	}
+
+	resetToken, errToken := utils.GenerateRandomToken(32)
+	if errToken != nil {
+		return authError(".AuthService->ForgotPassword()", errToken)
+	}
+
+	now := utils.Now()
+	ttl := time.Duration(config.AppConfig.PasswordResetTTL) * time.Second
+	_, err = s.passwordResetTokenStorage.Insert(ctx, &models.PasswordResetToken{
+		UserID:    currentUser.ID,
+		TokenHash: utils.HashToken(resetToken),
+		ExpiresAt: now + int(ttl.Seconds()),
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".AuthService->ForgotPassword()" + err.Path
+		return err
+	}
+
+	link, errURL := url.Parse(config.AppConfig.PasswordResetURL)
+	if errURL != nil {
+		return authError(".AuthService->ForgotPassword()", errURL)
+	}
+	query := link.Query()
+	query.Set("token", resetToken)
+	link.RawQuery = query.Encode()
+
+	errSend := s.mailSender.Send(ctx, &mailer.Message{
+		To:      currentUser.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password, it expires in %s and works once.\n\n%s\n\n"+
+			"If you didn't ask to reset your password you can ignore this email.\n", currentUser.Name, ttl, link.String()),
+	})
+	if errSend != nil {
+		return authError(".AuthService->ForgotPassword()", errSend)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with an emailed reset token. The token is single use, and every
+// session and refresh token of the user is revoked since whoever held the old password is logged out.
+// The token is consumed and the sessions revoked before the identity provider password changes.
+// A token that is unknown, expired or already used gets types.ErrPasswordResetTokenInvalid.
+func (s *Service) ResetPassword(ctx context.Context, params *datatransfers.ResetPasswordParams, client *datatransfers.ClientInfo) *types.Error {
+	resetToken, err := s.passwordResetTokenStorage.FindByTokenHash(ctx, utils.HashToken(params.Token))
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return authError(".AuthService->ResetPassword()", types.ErrPasswordResetTokenInvalid)
+		}
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+	if resetToken.UsedAt != nil || resetToken.ExpiresAt <= utils.Now() {
+		return authError(".AuthService->ResetPassword()", types.ErrPasswordResetTokenInvalid)
+	}
+
+	currentUser, err := s.userStorage.FindByID(ctx, resetToken.UserID)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+	if currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return authError(".AuthService->ResetPassword()", types.ErrPasswordResetTokenInvalid)
+	}
+
+	violations := s.passwordPolicy.Check("password", params.Password, &password.PolicyUser{
+		Name:     currentUser.Name,
+		Email:    currentUser.Email,
+		Username: currentUser.Username,
+	})
+	if violations != nil {
+		return &types.Error{
+			Path:    ".AuthService->ResetPassword()",
+			Message: violations.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	marked, err := s.passwordResetTokenStorage.MarkUsed(ctx, resetToken.ID)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+	if !marked {
+		return authError(".AuthService->ResetPassword()", types.ErrPasswordResetTokenInvalid)
+	}
+
+	provider, err := s.identityProviders.Get(currentUser.IdentityProvider)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	// Other links mailed before this one would still reset the new password
+	err = s.passwordResetTokenStorage.RevokeByUserID(ctx, currentUser.ID)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	err = s.tokenService.RevokeUserTokens(ctx, currentUser.ID)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventPasswordReset, &currentUser.ID, client, nil)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	// Sessions live in Redis outside the transaction, a failure after this only logs the user out
+	err = s.sessionService.RevokeAllSessions(ctx, currentUser.ID)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	// The provider isn't part of the transaction, so the password changes last. When it fails the
+	// transaction rolls back and the token stays usable, a changed password never leaves it replayable.
+	err = provider.SetPassword(ctx, currentUser.ClerkID, params.Password)
+	if err != nil {
+		err.Path = ".AuthService->ResetPassword()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// completeLogin finishes a login that passed its first factor with the authentication methods. Users with
+// MFA enabled get an MFA pending token for LoginMFA instead of a session.
+func (s *Service) completeLogin(ctx context.Context, currentUser *models.User, authMethods []string, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, currentUser.ID)
+	if err != nil {
+		err.Path = ".AuthService->completeLogin()" + err.Path
+		return nil, err
+	}
+	if mfaEnabled {
+		ttl := time.Duration(config.AppConfig.MFATokenTTL) * time.Second
+		mfaToken, _, errToken := config.GenerateMFAToken(currentUser.ID, authMethods, ttl)
+		if errToken != nil {
+			return nil, authError(".AuthService->completeLogin()", errToken)
+		}
+
+		return &datatransfers.LoginResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	return s.issueLogin(ctx, currentUser, authMethods, client)
+}
+
+// dropEmailLogin deletes the pending email login and its attempts, a login left behind expires by itself
+func (s *Service) dropEmailLogin(ctx context.Context, nonceHash string) {
+	for _, key := range []string{constants.EmailLoginCacheKey, constants.EmailLoginAttemptsCacheKey} {
+		if err := redis.DeleteCache(ctx, fmt.Sprintf(key, nonceHash)); err != nil {
+			log.Printf("Failed to delete email login: %v", err)
+		}
+	}
+}
+
+// hashEmailLoginSecret binds a link token or code to the browser nonce it was sent for
+func hashEmailLoginSecret(nonce string, secret string) string {
+	return utils.HashToken(nonce + ":" + secret)
+}
+
+// addAuthMethod adds the authentication method unless the login already used it
+func addAuthMethod(authMethods []string, method string) []string {
+	for _, m := range authMethods {
+		if m == method {
+			return authMethods
+		}
+	}
+	return append(authMethods, method)
+}
+
+// issueLogin starts a session for the authenticated user and issues its first tokens
+func (s *Service) issueLogin(ctx context.Context, currentUser *models.User, authMethods []string, client *datatransfers.ClientInfo) (*datatransfers.LoginResponse, *types.Error) {
+	currentSession, err := s.sessionService.CreateSession(ctx, currentUser.ID, authMethods, client)
+	if err != nil {
+		err.Path = ".AuthService->issueLogin()" + err.Path
+		return nil, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, currentUser, currentSession.ID)
+	if err != nil {
+		err.Path = ".AuthService->issueLogin()" + err.Path
+		return nil, err
+	}
+
+	return &datatransfers.LoginResponse{
+		SessionID: currentSession.ID,
+		User:      currentUser,
+		Token:     tokens,
+	}, nil
+}
+
+func authError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+func invalidCredentials(path string) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: types.ErrInvalidCredentials.Error(),
+		Error:   types.ErrInvalidCredentials,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewAuthService creates a new auth Service
+func NewAuthService(
+	userStorage user.Storage,
+	identityProviders identity.Providers,
+	sessionService session.ServiceInterface,
+	tokenService token.ServiceInterface,
+	passwordPolicy *password.Policy,
+	passwordResetTokenStorage passwordresettoken.Storage,
+	mailSender mailer.Sender,
+	auditService audit.ServiceInterface,
+	mfaService mfa.ServiceInterface,
+	passkeyService passkey.ServiceInterface,
+	lockoutService lockout.ServiceInterface,
+) *Service {
+	return &Service{
+		userStorage:       userStorage,
+		identityProviders: identityProviders,
+		sessionService:    sessionService,
+		tokenService:      tokenService,
+		passwordPolicy:    passwordPolicy,
+
+		passwordResetTokenStorage: passwordResetTokenStorage,
+		mailSender:                mailSender,
+		auditService:              auditService,
+		mfaService:                mfaService,
+		passkeyService:            passkeyService,
+		lockoutService:            lockoutService,
+	}
+}
@@ -0,0 +1,16 @@
+package lockout
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the lockout service interface
+type ServiceInterface interface {
+	Check(ctx context.Context, email string, ip string) *types.Error
+	RecordFailure(ctx context.Context, email string, userID *int, client *datatransfers.ClientInfo) *types.Error
+	RecordSuccess(ctx context.Context, email string) *types.Error
+	Unlock(ctx context.Context, userID int, adminID int, client *datatransfers.ClientInfo) *types.Error
+}
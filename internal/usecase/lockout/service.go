@@ -0,0 +1,210 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Service is the domain logic implementation of lockout Service interface. Accounts are keyed by
+// the email that was tried rather than by user, so unknown emails are throttled the same way as
+// existing accounts and the responses don't tell them apart.
+type Service struct {
+	userStorage  user.Storage
+	auditService audit.ServiceInterface
+}
+
+// Check rejects a login with types.ErrLoginThrottled while the account or IP is delayed or locked
+func (s *Service) Check(ctx context.Context, email string, ip string) *types.Error {
+	account := accountKey(email)
+	keys := []string{
+		fmt.Sprintf(constants.LoginLockAccountCacheKey, account),
+		fmt.Sprintf(constants.LoginDelayAccountCacheKey, account),
+	}
+	if ip != "" {
+		keys = append(keys,
+			fmt.Sprintf(constants.LoginLockIPCacheKey, ip),
+			fmt.Sprintf(constants.LoginDelayIPCacheKey, ip),
+		)
+	}
+
+	for _, key := range keys {
+		held, errCache := redis.GetCache(ctx, key)
+		if errCache != nil {
+			return lockoutError(".LockoutService->Check()", errCache)
+		}
+		if held != "" {
+			return lockoutError(".LockoutService->Check()", types.ErrLoginThrottled)
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the IP. Past LOGIN_DELAY_AFTER failures
+// the next try is held back for a delay that doubles with every failure, and at LOGIN_LOCKOUT_THRESHOLD
+// the account is locked for LOGIN_LOCKOUT_DURATION; IPs get their own, higher, limits. userID is the
+// account the email belongs to, nil when there is none. The lock events outlive the failed request.
+func (s *Service) RecordFailure(ctx context.Context, email string, userID *int, client *datatransfers.ClientInfo) *types.Error {
+	cfg := config.AppConfig
+	account := accountKey(email)
+
+	locked, err := s.countFailure(ctx,
+		fmt.Sprintf(constants.LoginFailuresAccountCacheKey, account),
+		fmt.Sprintf(constants.LoginDelayAccountCacheKey, account),
+		fmt.Sprintf(constants.LoginLockAccountCacheKey, account),
+		cfg.LoginDelayAfter, cfg.LoginLockoutThreshold,
+	)
+	if err != nil {
+		err.Path = ".LockoutService->RecordFailure()" + err.Path
+		return err
+	}
+	if locked {
+		err = s.auditService.RecordDetached(ctx, audit.EventAccountLocked, userID, client, types.Metadata{
+			"email":    email,
+			"duration": cfg.LoginLockoutDuration,
+		})
+		if err != nil {
+			err.Path = ".LockoutService->RecordFailure()" + err.Path
+			return err
+		}
+	}
+
+	if client == nil || client.IP == "" {
+		return nil
+	}
+	locked, err = s.countFailure(ctx,
+		fmt.Sprintf(constants.LoginFailuresIPCacheKey, client.IP),
+		fmt.Sprintf(constants.LoginDelayIPCacheKey, client.IP),
+		fmt.Sprintf(constants.LoginLockIPCacheKey, client.IP),
+		cfg.LoginIPDelayAfter, cfg.LoginIPLockoutThreshold,
+	)
+	if err != nil {
+		err.Path = ".LockoutService->RecordFailure()" + err.Path
+		return err
+	}
+	if locked {
+		err = s.auditService.RecordDetached(ctx, audit.EventIPLocked, nil, client, types.Metadata{
+			"duration": cfg.LoginLockoutDuration,
+		})
+		if err != nil {
+			err.Path = ".LockoutService->RecordFailure()" + err.Path
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets the failures of the account. Those of the IP stay, one good password
+// shouldn't let a stuffing run from the same IP start over.
+func (s *Service) RecordSuccess(ctx context.Context, email string) *types.Error {
+	account := accountKey(email)
+	for _, key := range []string{constants.LoginFailuresAccountCacheKey, constants.LoginDelayAccountCacheKey} {
+		if errCache := redis.DeleteCache(ctx, fmt.Sprintf(key, account)); errCache != nil {
+			return lockoutError(".LockoutService->RecordSuccess()", errCache)
+		}
+	}
+
+	return nil
+}
+
+// Unlock lifts the lockout and delays of the account of the user before the cooldown ends
+func (s *Service) Unlock(ctx context.Context, userID int, adminID int, client *datatransfers.ClientInfo) *types.Error {
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".LockoutService->Unlock()" + err.Path
+		return err
+	}
+
+	account := accountKey(currentUser.Email)
+	for _, key := range []string{constants.LoginLockAccountCacheKey, constants.LoginDelayAccountCacheKey, constants.LoginFailuresAccountCacheKey} {
+		if errCache := redis.DeleteCache(ctx, fmt.Sprintf(key, account)); errCache != nil {
+			return lockoutError(".LockoutService->Unlock()", errCache)
+		}
+	}
+
+	err = s.auditService.Record(ctx, audit.EventAccountUnlocked, &userID, client, types.Metadata{
+		"unlockedBy": adminID,
+	})
+	if err != nil {
+		err.Path = ".LockoutService->Unlock()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// countFailure counts a failure on counterKey and holds back the next try through delayKey, or through
+// lockKey once lockThreshold is reached. It returns true when this failure locked.
+func (s *Service) countFailure(ctx context.Context, counterKey string, delayKey string, lockKey string, delayAfter int, lockThreshold int) (bool, *types.Error) {
+	window := time.Duration(config.AppConfig.LoginFailureWindow) * time.Second
+	failures, errCache := redis.Increment(ctx, counterKey, window)
+	if errCache != nil {
+		return false, lockoutError(".LockoutService->countFailure()", errCache)
+	}
+
+	if failures >= int64(lockThreshold) {
+		duration := time.Duration(config.AppConfig.LoginLockoutDuration) * time.Second
+		if errCache := redis.SetCache(ctx, lockKey, 1, duration); errCache != nil {
+			return false, lockoutError(".LockoutService->countFailure()", errCache)
+		}
+		// The count starts over after the cooldown, so the next lock takes a full threshold again
+		if errCache := redis.DeleteCache(ctx, counterKey); errCache != nil {
+			return false, lockoutError(".LockoutService->countFailure()", errCache)
+		}
+		return failures == int64(lockThreshold), nil
+	}
+
+	if failures >= int64(delayAfter) {
+		if errCache := redis.SetCache(ctx, delayKey, 1, delay(int(failures)-delayAfter)); errCache != nil {
+			return false, lockoutError(".LockoutService->countFailure()", errCache)
+		}
+	}
+
+	return false, nil
+}
+
+// delay is LOGIN_DELAY_BASE doubled for every failure past the first delayed one, capped at LOGIN_DELAY_MAX
+func delay(step int) time.Duration {
+	seconds := config.AppConfig.LoginDelayBase
+	for i := 0; i < step && seconds < config.AppConfig.LoginDelayMax; i++ {
+		seconds *= 2
+	}
+	return time.Duration(min(seconds, config.AppConfig.LoginDelayMax)) * time.Second
+}
+
+// accountKey keys the counters of an account by the hashed email, so no email ends up in a Redis key
+func accountKey(email string) string {
+	return utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func lockoutError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewLockoutService creates a new lockout Service
+func NewLockoutService(
+	userStorage user.Storage,
+	auditService audit.ServiceInterface,
+) *Service {
+	return &Service{
+		userStorage:  userStorage,
+		auditService: auditService,
+	}
+}
@@ -0,0 +1,18 @@
+package mfa
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the MFA service interface
+type ServiceInterface interface {
+	Enroll(ctx context.Context, userID int) (*datatransfers.MFAEnrollment, *types.Error)
+	Confirm(ctx context.Context, userID int, code string, client *datatransfers.ClientInfo) ([]string, *types.Error)
+	IsEnabled(ctx context.Context, userID int) (bool, *types.Error)
+	Verify(ctx context.Context, userID int, code string, client *datatransfers.ClientInfo) *types.Error
+	Disable(ctx context.Context, userID int, password string, client *datatransfers.ClientInfo) *types.Error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, password string, client *datatransfers.ClientInfo) ([]string, *types.Error)
+}
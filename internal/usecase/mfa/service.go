@@ -0,0 +1,552 @@
+package mfa
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/mfarecoverycode"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/repository/usermfa"
+	"github.com/riskibarqy/bq-account-service/internal/totp"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/lockout"
+	"github.com/riskibarqy/bq-account-service/utils"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+
+	// recoveryCodeAlphabet leaves out the characters that are easily mistaken for each other
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+
+	// totpSkew is how many periods a code may be off, for clock drift
+	totpSkew = 1
+
+	// qrCodeScale is the size of a QR code module in pixels
+	qrCodeScale = 6
+)
+
+var errMalformedSecret = errors.New("malformed encrypted TOTP secret")
+
+// Service is the domain logic implementation of MFA Service interface
+type Service struct {
+	userStorage         user.Storage
+	identityProviders   identity.Providers
+	userMFAStorage      usermfa.Storage
+	recoveryCodeStorage mfarecoverycode.Storage
+	auditService        audit.ServiceInterface
+	lockoutService      lockout.ServiceInterface
+
+	// encryptionKey is the AES-256 key of the TOTP secrets
+	encryptionKey []byte
+}
+
+// Enroll starts a TOTP enrollment with a new secret, replacing an unconfirmed one.
+// MFA isn't enforced until the enrollment is confirmed with a code from the authenticator.
+func (s *Service) Enroll(ctx context.Context, userID int) (*datatransfers.MFAEnrollment, *types.Error) {
+	currentUser, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->Enroll()" + err.Path
+		return nil, err
+	}
+
+	userMFA, err := s.userMFAStorage.FindByUserID(ctx, userID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".MFAService->Enroll()" + err.Path
+		return nil, err
+	}
+	if userMFA != nil && userMFA.ConfirmedAt != nil {
+		return nil, mfaError(".MFAService->Enroll()", types.ErrMFAAlreadyEnabled)
+	}
+
+	secret, errSecret := totp.GenerateSecret()
+	if errSecret != nil {
+		return nil, mfaError(".MFAService->Enroll()", errSecret)
+	}
+	encryptedSecret, errEncrypt := s.encryptSecret(secret)
+	if errEncrypt != nil {
+		return nil, mfaError(".MFAService->Enroll()", errEncrypt)
+	}
+
+	now := utils.Now()
+	if userMFA == nil {
+		_, err = s.userMFAStorage.Insert(ctx, &models.UserMFA{
+			UserID:    userID,
+			Secret:    encryptedSecret,
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+	} else {
+		userMFA.Secret = encryptedSecret
+		userMFA.LastUsedStep = 0
+		userMFA.UpdatedAt = &now
+		_, err = s.userMFAStorage.Update(ctx, userMFA)
+	}
+	if err != nil {
+		err.Path = ".MFAService->Enroll()" + err.Path
+		return nil, err
+	}
+
+	uri := totp.URI(config.AppConfig.MFAIssuer, currentUser.Email, secret)
+	qr, errQR := qrcode.New(uri, qrcode.Medium)
+	if errQR != nil {
+		return nil, mfaError(".MFAService->Enroll()", errQR)
+	}
+	// A negative size scales every module to that many pixels instead of fitting a fixed size
+	qrPNG, errQR := qr.PNG(-qrCodeScale)
+	if errQR != nil {
+		return nil, mfaError(".MFAService->Enroll()", errQR)
+	}
+
+	return &datatransfers.MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrPNG),
+	}, nil
+}
+
+// Confirm enables MFA once the user proves the authenticator works, and returns the first recovery codes
+func (s *Service) Confirm(ctx context.Context, userID int, code string, client *datatransfers.ClientInfo) ([]string, *types.Error) {
+	userMFA, err := s.userMFAStorage.FindByUserID(ctx, userID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, mfaError(".MFAService->Confirm()", types.ErrMFANotEnrolled)
+		}
+		err.Path = ".MFAService->Confirm()" + err.Path
+		return nil, err
+	}
+	if userMFA.ConfirmedAt != nil {
+		return nil, mfaError(".MFAService->Confirm()", types.ErrMFAAlreadyEnabled)
+	}
+
+	step, err := s.validateTOTP(userMFA, code)
+	if err != nil {
+		err.Path = ".MFAService->Confirm()" + err.Path
+		return nil, err
+	}
+
+	now := utils.Now()
+	userMFA.ConfirmedAt = &now
+	userMFA.LastUsedStep = step
+	userMFA.UpdatedAt = &now
+	_, err = s.userMFAStorage.Update(ctx, userMFA)
+	if err != nil {
+		err.Path = ".MFAService->Confirm()" + err.Path
+		return nil, err
+	}
+
+	recoveryCodes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->Confirm()" + err.Path
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventMFAEnabled, &userID, client, nil)
+	if err != nil {
+		err.Path = ".MFAService->Confirm()" + err.Path
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// IsEnabled tells whether the user has a confirmed authenticator
+func (s *Service) IsEnabled(ctx context.Context, userID int) (bool, *types.Error) {
+	userMFA, err := s.userMFAStorage.FindByUserID(ctx, userID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return false, nil
+		}
+		err.Path = ".MFAService->IsEnabled()" + err.Path
+		return false, err
+	}
+
+	return userMFA.ConfirmedAt != nil, nil
+}
+
+// Verify checks a second factor, a TOTP code or one of the recovery codes which is then used up.
+// A wrong code gets types.ErrMFACodeInvalid and counts as a failed login of the user, so a locked
+// account gets types.ErrLoginThrottled however many MFA pending tokens the password yields.
+func (s *Service) Verify(ctx context.Context, userID int, code string, client *datatransfers.ClientInfo) *types.Error {
+	currentUser, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->Verify()" + err.Path
+		return err
+	}
+
+	err = s.lockoutService.Check(ctx, currentUser.Email, clientIP(client))
+	if err != nil {
+		err.Path = ".MFAService->Verify()" + err.Path
+		return err
+	}
+
+	err = s.verifyCode(ctx, userID, code, client)
+	if err != nil && err.Error == types.ErrMFACodeInvalid {
+		if errFailure := s.lockoutService.RecordFailure(ctx, currentUser.Email, &currentUser.ID, client); errFailure != nil {
+			errFailure.Path = ".MFAService->Verify()" + errFailure.Path
+			return errFailure
+		}
+	}
+	if err != nil {
+		err.Path = ".MFAService->Verify()" + err.Path
+		return err
+	}
+
+	err = s.lockoutService.RecordSuccess(ctx, currentUser.Email)
+	if err != nil {
+		err.Path = ".MFAService->Verify()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// verifyCode checks the code against the authenticator of the user, or uses up the recovery code it is
+func (s *Service) verifyCode(ctx context.Context, userID int, code string, client *datatransfers.ClientInfo) *types.Error {
+	userMFA, err := s.userMFAStorage.FindByUserID(ctx, userID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".MFAService->verifyCode()" + err.Path
+		return err
+	}
+	if userMFA == nil || userMFA.ConfirmedAt == nil {
+		return mfaError(".MFAService->verifyCode()", types.ErrMFANotEnabled)
+	}
+
+	if len(code) == totp.Digits {
+		step, err := s.validateTOTP(userMFA, code)
+		if err != nil {
+			err.Path = ".MFAService->verifyCode()" + err.Path
+			return err
+		}
+
+		marked, err := s.userMFAStorage.MarkStepUsed(ctx, userMFA.ID, step)
+		if err != nil {
+			err.Path = ".MFAService->verifyCode()" + err.Path
+			return err
+		}
+		if !marked {
+			return mfaError(".MFAService->verifyCode()", types.ErrMFACodeInvalid)
+		}
+
+		return nil
+	}
+
+	marked, err := s.recoveryCodeStorage.MarkUsed(ctx, userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		err.Path = ".MFAService->verifyCode()" + err.Path
+		return err
+	}
+	if !marked {
+		return mfaError(".MFAService->verifyCode()", types.ErrMFACodeInvalid)
+	}
+
+	err = s.auditService.Record(ctx, audit.EventMFARecoveryCodeUsed, &userID, client, nil)
+	if err != nil {
+		err.Path = ".MFAService->verifyCode()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// Disable removes the authenticator and the recovery codes of the user after checking the password
+func (s *Service) Disable(ctx context.Context, userID int, password string, client *datatransfers.ClientInfo) *types.Error {
+	currentUser, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+
+	err = s.reauthenticate(ctx, currentUser, password, client)
+	if err != nil {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+
+	userMFA, err := s.userMFAStorage.FindByUserID(ctx, userID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+	if userMFA == nil || userMFA.ConfirmedAt == nil {
+		return mfaError(".MFAService->Disable()", types.ErrMFANotEnabled)
+	}
+
+	err = s.userMFAStorage.Delete(ctx, userMFA.ID)
+	if err != nil {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+
+	err = s.recoveryCodeStorage.DeleteByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventMFADisabled, &userID, client, nil)
+	if err != nil {
+		err.Path = ".MFAService->Disable()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after checking the password
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int, password string, client *datatransfers.ClientInfo) ([]string, *types.Error) {
+	currentUser, err := s.findUser(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	err = s.reauthenticate(ctx, currentUser, password, client)
+	if err != nil {
+		err.Path = ".MFAService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+	if !enabled {
+		return nil, mfaError(".MFAService->RegenerateRecoveryCodes()", types.ErrMFANotEnabled)
+	}
+
+	recoveryCodes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventMFARecoveryCodesRegenerated, &userID, client, nil)
+	if err != nil {
+		err.Path = ".MFAService->RegenerateRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// findUser finds the user, a deleted user counts as not found
+func (s *Service) findUser(ctx context.Context, userID int) (*models.User, *types.Error) {
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->findUser()" + err.Path
+		return nil, err
+	}
+	if currentUser.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
+
+	return currentUser, nil
+}
+
+// reauthenticate checks the password of the user, a wrong one gets types.ErrWrongPassword. Wrong
+// passwords count as failed logins, so a stolen access token doesn't get unlimited guesses.
+func (s *Service) reauthenticate(ctx context.Context, currentUser *models.User, password string, client *datatransfers.ClientInfo) *types.Error {
+	err := s.lockoutService.Check(ctx, currentUser.Email, clientIP(client))
+	if err != nil {
+		err.Path = ".MFAService->reauthenticate()" + err.Path
+		return err
+	}
+
+	provider, err := s.identityProviders.Get(currentUser.IdentityProvider)
+	if err != nil {
+		err.Path = ".MFAService->reauthenticate()" + err.Path
+		return err
+	}
+
+	remote, err := provider.VerifyCredentials(ctx, currentUser.Email, password)
+	if err != nil && err.Error != types.ErrInvalidCredentials {
+		err.Path = ".MFAService->reauthenticate()" + err.Path
+		return err
+	}
+	if err != nil || remote.ID != currentUser.ClerkID {
+		err = s.lockoutService.RecordFailure(ctx, currentUser.Email, &currentUser.ID, client)
+		if err != nil {
+			err.Path = ".MFAService->reauthenticate()" + err.Path
+			return err
+		}
+		return mfaError(".MFAService->reauthenticate()", types.ErrWrongPassword)
+	}
+
+	err = s.lockoutService.RecordSuccess(ctx, currentUser.Email)
+	if err != nil {
+		err.Path = ".MFAService->reauthenticate()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// validateTOTP checks the code against the secret of the authenticator and returns its time step,
+// codes from the last accepted step or before are replays
+func (s *Service) validateTOTP(userMFA *models.UserMFA, code string) (int64, *types.Error) {
+	secret, errDecrypt := s.decryptSecret(userMFA.Secret)
+	if errDecrypt != nil {
+		return 0, mfaError(".MFAService->validateTOTP()", errDecrypt)
+	}
+
+	step, ok, errValidate := totp.Validate(secret, code, time.Now(), totpSkew)
+	if errValidate != nil {
+		return 0, mfaError(".MFAService->validateTOTP()", errValidate)
+	}
+	if !ok || step <= userMFA.LastUsedStep {
+		return 0, mfaError(".MFAService->validateTOTP()", types.ErrMFACodeInvalid)
+	}
+
+	return step, nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of the user and stores the hashes of new ones
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID int) ([]string, *types.Error) {
+	err := s.recoveryCodeStorage.DeleteByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".MFAService->replaceRecoveryCodes()" + err.Path
+		return nil, err
+	}
+
+	now := utils.Now()
+	recoveryCodes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		code, errCode := generateRecoveryCode()
+		if errCode != nil {
+			return nil, mfaError(".MFAService->replaceRecoveryCodes()", errCode)
+		}
+
+		_, err = s.recoveryCodeStorage.Insert(ctx, &models.MFARecoveryCode{
+			UserID:    userID,
+			CodeHash:  utils.HashToken(normalizeRecoveryCode(code)),
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+		if err != nil {
+			err.Path = ".MFAService->replaceRecoveryCodes()" + err.Path
+			return nil, err
+		}
+		recoveryCodes[i] = code
+	}
+
+	return recoveryCodes, nil
+}
+
+// encryptSecret seals the TOTP secret with AES-GCM, the nonce is prepended to the ciphertext
+func (s *Service) encryptSecret(secret string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *Service) decryptSecret(encrypted string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errMalformedSecret
+	}
+
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func (s *Service) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// generateRecoveryCode returns a random code formatted as "xxxxx-xxxxx"
+func generateRecoveryCode() (string, error) {
+	code := make([]byte, recoveryCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+	return string(code[:recoveryCodeLength/2]) + "-" + string(code[recoveryCodeLength/2:]), nil
+}
+
+// normalizeRecoveryCode drops the separator and case, so codes are accepted however they are typed
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// clientIP is the IP the lockout counts against, none when the client is unknown
+func clientIP(client *datatransfers.ClientInfo) string {
+	if client == nil {
+		return ""
+	}
+	return client.IP
+}
+
+func mfaError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewMFAService creates a new MFA Service, the TOTP secrets are encrypted with a key derived from encryptionKey
+func NewMFAService(
+	userStorage user.Storage,
+	identityProviders identity.Providers,
+	userMFAStorage usermfa.Storage,
+	recoveryCodeStorage mfarecoverycode.Storage,
+	auditService audit.ServiceInterface,
+	lockoutService lockout.ServiceInterface,
+	encryptionKey string,
+) *Service {
+	key := sha256.Sum256([]byte(encryptionKey))
+	return &Service{
+		userStorage:         userStorage,
+		identityProviders:   identityProviders,
+		userMFAStorage:      userMFAStorage,
+		recoveryCodeStorage: recoveryCodeStorage,
+		auditService:        auditService,
+		lockoutService:      lockoutService,
+		encryptionKey:       key[:],
+	}
+}
@@ -0,0 +1,19 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the oauth service interface
+type ServiceInterface interface {
+	Token(ctx context.Context, params *datatransfers.OAuthTokenParams, client *datatransfers.ClientInfo) (*datatransfers.OAuthTokenResponse, *types.Error)
+	ValidateAuthorization(ctx context.Context, params *datatransfers.OAuthAuthorizeParams) (*models.App, []string, *types.Error)
+	Authorize(ctx context.Context, userID int, sessionID string, params *datatransfers.OAuthAuthorizeParams, client *datatransfers.ClientInfo) (*datatransfers.OAuthAuthorizeResponse, *types.Error)
+	ListConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, *types.Error)
+	RevokeConsent(ctx context.Context, userID int, appID int, client *datatransfers.ClientInfo) *types.Error
+	UserInfo(ctx context.Context, userID int, scope *string) (*datatransfers.UserInfo, *types.Error)
+}
@@ -0,0 +1,515 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/appredirecturi"
+	"github.com/riskibarqy/bq-account-service/internal/repository/oauthconsent"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/app"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Grant types of the token endpoint
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeAuthorizationCode = "authorization_code"
+)
+
+// ResponseTypeCode is the only response type of the authorize endpoint, CodeChallengeMethodS256 the only
+// PKCE method, plain challenges would leak the verifier with the authorization request
+const (
+	ResponseTypeCode        = "code"
+	CodeChallengeMethodS256 = "S256"
+)
+
+// Scopes of OpenID Connect, openid asks for an ID token and the others for the claims /userinfo returns
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+var (
+	// codeChallengePattern is the base64url of a sha256 without padding
+	codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+	// codeVerifierPattern is the code verifier of RFC 7636 section 4.1
+	codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+)
+
+// authorizationCode is what an authorization code was issued for, kept in Redis under the hash of the code.
+// AuthTime and AuthMethods come from the session the user consented in, for the ID token.
+type authorizationCode struct {
+	AppID         int      `json:"appId"`
+	UserID        int      `json:"userId"`
+	RedirectURI   string   `json:"redirectUri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"codeChallenge"`
+	Nonce         string   `json:"nonce,omitempty"`
+	AuthTime      int      `json:"authTime"`
+	AuthMethods   []string `json:"authMethods,omitempty"`
+}
+
+// Service is the domain logic implementation of oauth Service interface
+type Service struct {
+	appService         app.AppServiceInterface
+	redirectURIStorage appredirecturi.Storage
+	consentStorage     oauthconsent.Storage
+	userStorage        user.Storage
+	sessionService     session.ServiceInterface
+	auditService       audit.ServiceInterface
+}
+
+// Token handles a request to the token endpoint, authenticating the client before looking at the grant
+func (s *Service) Token(ctx context.Context, params *datatransfers.OAuthTokenParams, client *datatransfers.ClientInfo) (*datatransfers.OAuthTokenResponse, *types.Error) {
+	currentApp, err := s.appService.AuthenticateClient(ctx, params.ClientID, params.ClientSecret)
+	if err != nil {
+		err.Path = ".OAuthService->Token()" + err.Path
+		return nil, err
+	}
+
+	var result *datatransfers.OAuthTokenResponse
+	switch params.GrantType {
+	case GrantTypeClientCredentials:
+		result, err = s.clientCredentials(currentApp, params.Scope)
+	case GrantTypeAuthorizationCode:
+		result, err = s.exchangeCode(ctx, currentApp, params, client)
+	default:
+		err = oauthError(".OAuthService->Token()", types.ErrUnsupportedGrantType)
+	}
+	if err != nil {
+		err.Path = ".OAuthService->Token()" + err.Path
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// clientCredentials issues a token whose subject is the app itself
+func (s *Service) clientCredentials(currentApp *models.App, scope string) (*datatransfers.OAuthTokenResponse, *types.Error) {
+	// An app asking for nothing in particular gets every scope it is allowed
+	scopes := currentApp.AllowedScopes
+	if strings.TrimSpace(scope) != "" {
+		var err *types.Error
+		scopes, err = requestedScopes(currentApp, scope)
+		if err != nil {
+			err.Path = ".OAuthService->clientCredentials()" + err.Path
+			return nil, err
+		}
+	}
+
+	accessToken, errToken := config.GenerateClientToken(currentApp, scopes)
+	if errToken != nil {
+		return nil, oauthError(".OAuthService->clientCredentials()", errToken)
+	}
+
+	return &datatransfers.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   config.AppConfig.AccessTokenTTL,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// exchangeCode issues a token the user granted to the app for an authorization code. The code works once, only
+// for the app it was issued to, with the same redirect URI and with the verifier of its code challenge. Every
+// exchange gets its own session, which the user can revoke like any other. No refresh token is issued, the app
+// sends the user through /oauth/authorize again, which doesn't ask for consent a second time. With the openid
+// scope an ID token comes along.
+func (s *Service) exchangeCode(ctx context.Context, currentApp *models.App, params *datatransfers.OAuthTokenParams, client *datatransfers.ClientInfo) (*datatransfers.OAuthTokenResponse, *types.Error) {
+	if params.Code == "" || !codeVerifierPattern.MatchString(params.CodeVerifier) {
+		return nil, oauthError(".OAuthService->exchangeCode()", types.ErrInvalidGrant)
+	}
+
+	cached, errCache := redis.TakeCache(ctx, fmt.Sprintf(constants.OAuthCodeCacheKey, utils.HashToken(params.Code)))
+	if errCache != nil {
+		return nil, oauthError(".OAuthService->exchangeCode()", errCache)
+	}
+	if cached == "" {
+		return nil, oauthError(".OAuthService->exchangeCode()", types.ErrInvalidGrant)
+	}
+
+	code := &authorizationCode{}
+	if errJSON := jsoniter.Unmarshal([]byte(cached), code); errJSON != nil {
+		return nil, oauthError(".OAuthService->exchangeCode()", errJSON)
+	}
+
+	challenge := sha256.Sum256([]byte(params.CodeVerifier))
+	verified := subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(code.CodeChallenge)) == 1
+	if code.AppID != currentApp.ID || code.RedirectURI != params.RedirectURI || !verified {
+		return nil, oauthError(".OAuthService->exchangeCode()", types.ErrInvalidGrant)
+	}
+
+	currentUser, err := s.userStorage.FindByID(ctx, code.UserID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".OAuthService->exchangeCode()" + err.Path
+		return nil, err
+	}
+	if err != nil || currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil, oauthError(".OAuthService->exchangeCode()", types.ErrInvalidGrant)
+	}
+
+	sessionClient := &datatransfers.ClientInfo{Device: currentApp.Name}
+	if client != nil {
+		sessionClient.UserAgent = client.UserAgent
+		sessionClient.IP = client.IP
+	}
+	currentSession, err := s.sessionService.CreateSession(ctx, currentUser.ID, code.AuthMethods, sessionClient)
+	if err != nil {
+		err.Path = ".OAuthService->exchangeCode()" + err.Path
+		return nil, err
+	}
+
+	accessToken, errToken := config.GenerateDelegatedToken(currentUser, currentSession.ID, currentApp, code.Scopes)
+	if errToken != nil {
+		return nil, oauthError(".OAuthService->exchangeCode()", errToken)
+	}
+
+	result := &datatransfers.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   config.AppConfig.AccessTokenTTL,
+		Scope:       strings.Join(code.Scopes, " "),
+	}
+
+	if contains(code.Scopes, ScopeOpenID) {
+		result.IDToken, errToken = config.GenerateIDToken(currentUser, currentApp, code.Nonce, code.AuthTime, code.AuthMethods)
+		if errToken != nil {
+			return nil, oauthError(".OAuthService->exchangeCode()", errToken)
+		}
+	}
+
+	return result, nil
+}
+
+// ValidateAuthorization checks an authorization request and returns its app and scopes. With types.ErrInvalidClient
+// or types.ErrInvalidRedirectURI the redirect URI can't be trusted and the error has to be shown to the user, the
+// other errors are sent back to the redirect URI.
+func (s *Service) ValidateAuthorization(ctx context.Context, params *datatransfers.OAuthAuthorizeParams) (*models.App, []string, *types.Error) {
+	currentApp, err := s.appService.GetAppByClientID(ctx, params.ClientID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, nil, oauthError(".OAuthService->ValidateAuthorization()", types.ErrInvalidClient)
+		}
+		err.Path = ".OAuthService->ValidateAuthorization()" + err.Path
+		return nil, nil, err
+	}
+
+	// Redirect URIs are compared exactly, as they were registered
+	_, err = s.redirectURIStorage.FindByAppIDAndURI(ctx, currentApp.ID, params.RedirectURI)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, nil, oauthError(".OAuthService->ValidateAuthorization()", types.ErrInvalidRedirectURI)
+		}
+		err.Path = ".OAuthService->ValidateAuthorization()" + err.Path
+		return nil, nil, err
+	}
+
+	if params.ResponseType != ResponseTypeCode {
+		return nil, nil, oauthError(".OAuthService->ValidateAuthorization()", types.ErrUnsupportedResponseType)
+	}
+	if params.CodeChallengeMethod != CodeChallengeMethodS256 || !codeChallengePattern.MatchString(params.CodeChallenge) {
+		return nil, nil, oauthError(".OAuthService->ValidateAuthorization()", types.ErrPKCERequired)
+	}
+
+	scopes, err := requestedScopes(currentApp, params.Scope)
+	if err != nil {
+		err.Path = ".OAuthService->ValidateAuthorization()" + err.Path
+		return nil, nil, err
+	}
+
+	return currentApp, scopes, nil
+}
+
+// Authorize answers an authorization request the user made on the consent page from the session. Scopes the user
+// granted the app before aren't asked again; new ones need Consent, which adds them to the consent record of the app.
+// The answer is where to send the browser: the redirect URI with a code, or with access_denied.
+func (s *Service) Authorize(ctx context.Context, userID int, sessionID string, params *datatransfers.OAuthAuthorizeParams, client *datatransfers.ClientInfo) (*datatransfers.OAuthAuthorizeResponse, *types.Error) {
+	currentApp, scopes, err := s.ValidateAuthorization(ctx, params)
+	if err != nil {
+		err.Path = ".OAuthService->Authorize()" + err.Path
+		return nil, err
+	}
+
+	if params.Deny {
+		return &datatransfers.OAuthAuthorizeResponse{
+			RedirectURI: BuildRedirect(params.RedirectURI, url.Values{
+				"error": {"access_denied"},
+				"state": {params.State},
+			}),
+		}, nil
+	}
+
+	consent, err := s.consentStorage.FindByUserIDAndAppID(ctx, userID, currentApp.ID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".OAuthService->Authorize()" + err.Path
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, scope := range scopes {
+		if consent == nil || !contains(consent.Scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) > 0 || consent == nil {
+		if !params.Consent {
+			return &datatransfers.OAuthAuthorizeResponse{
+				ConsentRequired: true,
+				AppName:         currentApp.Name,
+				Scopes:          missing,
+			}, nil
+		}
+
+		err = s.grantConsent(ctx, userID, currentApp.ID, consent, missing, client)
+		if err != nil {
+			err.Path = ".OAuthService->Authorize()" + err.Path
+			return nil, err
+		}
+	}
+
+	currentSession, err := s.sessionService.GetSession(ctx, sessionID)
+	if err != nil {
+		err.Path = ".OAuthService->Authorize()" + err.Path
+		return nil, err
+	}
+
+	code, errToken := utils.GenerateRandomToken(32)
+	if errToken != nil {
+		return nil, oauthError(".OAuthService->Authorize()", errToken)
+	}
+
+	cached, _ := jsoniter.Marshal(&authorizationCode{
+		AppID:         currentApp.ID,
+		UserID:        userID,
+		RedirectURI:   params.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: params.CodeChallenge,
+		Nonce:         params.Nonce,
+		AuthTime:      currentSession.CreatedAt,
+		AuthMethods:   currentSession.AuthMethods,
+	})
+	ttl := time.Duration(config.AppConfig.OAuthCodeTTL) * time.Second
+	if errCache := redis.SetCache(ctx, fmt.Sprintf(constants.OAuthCodeCacheKey, utils.HashToken(code)), cached, ttl); errCache != nil {
+		return nil, oauthError(".OAuthService->Authorize()", errCache)
+	}
+
+	return &datatransfers.OAuthAuthorizeResponse{
+		RedirectURI: BuildRedirect(params.RedirectURI, url.Values{
+			"code":  {code},
+			"state": {params.State},
+		}),
+	}, nil
+}
+
+// grantConsent adds the scopes to the consent the user gave the app, creating it on the first grant
+func (s *Service) grantConsent(ctx context.Context, userID int, appID int, consent *models.OAuthConsent, scopes []string, client *datatransfers.ClientInfo) *types.Error {
+	var err *types.Error
+	now := utils.Now()
+	if consent == nil {
+		_, err = s.consentStorage.Insert(ctx, &models.OAuthConsent{
+			UserID:    userID,
+			AppID:     appID,
+			Scopes:    append(types.StringArray{}, scopes...),
+			CreatedAt: now,
+			UpdatedAt: &now,
+		})
+	} else {
+		consent.Scopes = append(consent.Scopes, scopes...)
+		consent.UpdatedAt = &now
+		_, err = s.consentStorage.Update(ctx, consent)
+	}
+	if err != nil {
+		err.Path = ".OAuthService->grantConsent()" + err.Path
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventOAuthConsentGranted, &userID, client, types.Metadata{
+		"appId":  appID,
+		"scopes": scopes,
+	})
+	if err != nil {
+		err.Path = ".OAuthService->grantConsent()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// ListConsents lists the apps the user granted scopes to
+func (s *Service) ListConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, *types.Error) {
+	consents, err := s.consentStorage.FindByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".OAuthService->ListConsents()" + err.Path
+		return nil, err
+	}
+
+	for _, consent := range consents {
+		consent.App, err = s.appService.GetApp(ctx, consent.AppID)
+		if err != nil && err.Error != data.ErrNotFound {
+			err.Path = ".OAuthService->ListConsents()" + err.Path
+			return nil, err
+		}
+	}
+
+	return consents, nil
+}
+
+// RevokeConsent forgets the scopes the user granted the app, its next authorization request asks for consent again.
+// Tokens already issued keep working until they expire or their session is revoked.
+func (s *Service) RevokeConsent(ctx context.Context, userID int, appID int, client *datatransfers.ClientInfo) *types.Error {
+	consent, err := s.consentStorage.FindByUserIDAndAppID(ctx, userID, appID)
+	if err != nil {
+		err.Path = ".OAuthService->RevokeConsent()" + err.Path
+		return err
+	}
+
+	err = s.consentStorage.Delete(ctx, consent.ID)
+	if err != nil {
+		err.Path = ".OAuthService->RevokeConsent()" + err.Path
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventOAuthConsentRevoked, &userID, client, types.Metadata{
+		"appId": appID,
+	})
+	if err != nil {
+		err.Path = ".OAuthService->RevokeConsent()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// UserInfo returns the claims about the user for the space separated scopes of a token the user granted an app.
+// The scope is nil for the tokens of the user itself, which see every claim.
+func (s *Service) UserInfo(ctx context.Context, userID int, scope *string) (*datatransfers.UserInfo, *types.Error) {
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".OAuthService->UserInfo()" + err.Path
+		return nil, err
+	}
+
+	all := scope == nil
+	var scopes []string
+	if !all {
+		scopes = strings.Fields(*scope)
+	}
+
+	info := &datatransfers.UserInfo{
+		Subject: strconv.Itoa(currentUser.ID),
+	}
+	if all || contains(scopes, ScopeProfile) {
+		info.Name = currentUser.Name
+		info.PreferredUsername = currentUser.Username
+	}
+	if (all || contains(scopes, ScopeEmail)) && currentUser.Email != "" {
+		info.Email = currentUser.Email
+		info.EmailVerified = &currentUser.IsVerified
+	}
+	if (all || contains(scopes, ScopePhone)) && currentUser.Phone != "" {
+		info.PhoneNumber = currentUser.Phone
+		info.PhoneNumberVerified = &currentUser.IsPhoneVerified
+	}
+
+	return info, nil
+}
+
+// BuildRedirect adds the parameters to the query of the redirect URI, leaving out empty ones such as a missing state
+func BuildRedirect(redirectURI string, params url.Values) string {
+	parsed, errParse := url.Parse(redirectURI)
+	if errParse != nil {
+		return redirectURI
+	}
+
+	query := parsed.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// requestedScopes returns the space separated scopes, which must all be allowed for the app. Users are never
+// asked to grant scopes by default, so no scope at all is invalid too.
+func requestedScopes(currentApp *models.App, scope string) ([]string, *types.Error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return nil, oauthError(".OAuthService->requestedScopes()", types.ErrInvalidScope)
+	}
+
+	unique := []string{}
+	for _, requested := range scopes {
+		if !contains(currentApp.AllowedScopes, requested) {
+			return nil, oauthError(".OAuthService->requestedScopes()", types.ErrInvalidScope)
+		}
+		if !contains(unique, requested) {
+			unique = append(unique, requested)
+		}
+	}
+
+	return unique, nil
+}
+
+// contains tells whether the scope is one of the scopes
+func contains(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func oauthError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewOAuthService creates a new oauth Service
+func NewOAuthService(
+	appService app.AppServiceInterface,
+	redirectURIStorage appredirecturi.Storage,
+	consentStorage oauthconsent.Storage,
+	userStorage user.Storage,
+	sessionService session.ServiceInterface,
+	auditService audit.ServiceInterface,
+) *Service {
+	return &Service{
+		appService:         appService,
+		redirectURIStorage: redirectURIStorage,
+		consentStorage:     consentStorage,
+		userStorage:        userStorage,
+		sessionService:     sessionService,
+		auditService:       auditService,
+	}
+}
@@ -0,0 +1,21 @@
+package passkey
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
+)
+
+// ServiceInterface represents the passkey service interface
+type ServiceInterface interface {
+	RegistrationOptions(ctx context.Context, userID int) (*webauthn.CreationOptions, *types.Error)
+	Register(ctx context.Context, userID int, params *datatransfers.PasskeyRegisterParams, client *datatransfers.ClientInfo) (*models.WebAuthnCredential, *types.Error)
+	ListPasskeys(ctx context.Context, userID int) ([]*models.WebAuthnCredential, *types.Error)
+	RenamePasskey(ctx context.Context, userID int, passkeyID int, name string) (*models.WebAuthnCredential, *types.Error)
+	RemovePasskey(ctx context.Context, userID int, passkeyID int, client *datatransfers.ClientInfo) *types.Error
+	LoginOptions(ctx context.Context) (*webauthn.RequestOptions, *types.Error)
+	VerifyLogin(ctx context.Context, response *webauthn.AssertionResponse, client *datatransfers.ClientInfo) (*models.User, *types.Error)
+}
@@ -0,0 +1,352 @@
+package passkey
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/repository/webauthncredential"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/audit"
+	"github.com/riskibarqy/bq-account-service/internal/webauthn"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// challengeSize is the number of random bytes of a ceremony challenge
+const challengeSize = 32
+
+// Service is the domain logic implementation of passkey Service interface
+type Service struct {
+	userStorage       user.Storage
+	credentialStorage webauthncredential.Storage
+	auditService      audit.ServiceInterface
+	relyingParty      *webauthn.RelyingParty
+
+	// challengeTTL is how long the options of a ceremony can be answered
+	challengeTTL time.Duration
+}
+
+// RegistrationOptions starts a passkey registration, the options are passed to navigator.credentials.create().
+// A new call replaces the pending challenge of the user.
+func (s *Service) RegistrationOptions(ctx context.Context, userID int) (*webauthn.CreationOptions, *types.Error) {
+	currentUser, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".PasskeyService->RegistrationOptions()" + err.Path
+		return nil, err
+	}
+
+	credentials, err := s.credentialStorage.FindByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".PasskeyService->RegistrationOptions()" + err.Path
+		return nil, err
+	}
+
+	challenge, errToken := utils.GenerateRandomToken(challengeSize)
+	if errToken != nil {
+		return nil, passkeyError(".PasskeyService->RegistrationOptions()", errToken)
+	}
+	errCache := redis.SetCache(ctx, fmt.Sprintf(constants.WebAuthnRegistrationCacheKey, userID), challenge, s.challengeTTL)
+	if errCache != nil {
+		return nil, passkeyError(".PasskeyService->RegistrationOptions()", errCache)
+	}
+
+	return s.relyingParty.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          userHandle(userID),
+		Name:        currentUser.Email,
+		DisplayName: currentUser.Name,
+	}, descriptors(credentials)), nil
+}
+
+// Register verifies the response to the pending registration options of the user and stores the new passkey
+func (s *Service) Register(ctx context.Context, userID int, params *datatransfers.PasskeyRegisterParams, client *datatransfers.ClientInfo) (*models.WebAuthnCredential, *types.Error) {
+	challenge, errCache := redis.TakeCache(ctx, fmt.Sprintf(constants.WebAuthnRegistrationCacheKey, userID))
+	if errCache != nil {
+		return nil, passkeyError(".PasskeyService->Register()", errCache)
+	}
+	if challenge == "" {
+		return nil, passkeyError(".PasskeyService->Register()", types.ErrPasskeyChallengeExpired)
+	}
+
+	verified, errVerify := s.relyingParty.VerifyRegistration(challenge, &params.Credential)
+	if errVerify != nil {
+		return nil, invalidPasskey(".PasskeyService->Register()", errVerify)
+	}
+
+	credentialID := webauthn.EncodeBase64URL(verified.ID)
+	existing, err := s.credentialStorage.FindByCredentialID(ctx, credentialID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".PasskeyService->Register()" + err.Path
+		return nil, err
+	}
+	if existing != nil {
+		return nil, passkeyError(".PasskeyService->Register()", types.ErrPasskeyAlreadyRegistered)
+	}
+
+	now := utils.Now()
+	credential, err := s.credentialStorage.Insert(ctx, &models.WebAuthnCredential{
+		UserID:         userID,
+		CredentialID:   credentialID,
+		PublicKey:      webauthn.EncodeBase64URL(verified.PublicKey),
+		SignCount:      int64(verified.SignCount),
+		AAGUID:         formatAAGUID(verified.AAGUID),
+		Transports:     strings.Join(verified.Transports, ","),
+		BackupEligible: verified.BackupEligible,
+		Name:           params.Name,
+		CreatedAt:      now,
+		UpdatedAt:      &now,
+	})
+	if err != nil {
+		err.Path = ".PasskeyService->Register()" + err.Path
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventPasskeyAdded, &userID, client, types.Metadata{
+		"passkeyId": credential.ID,
+		"aaguid":    credential.AAGUID,
+	})
+	if err != nil {
+		err.Path = ".PasskeyService->Register()" + err.Path
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// ListPasskeys lists the passkeys of the user
+func (s *Service) ListPasskeys(ctx context.Context, userID int) ([]*models.WebAuthnCredential, *types.Error) {
+	credentials, err := s.credentialStorage.FindByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".PasskeyService->ListPasskeys()" + err.Path
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// RenamePasskey renames a passkey of the user
+func (s *Service) RenamePasskey(ctx context.Context, userID int, passkeyID int, name string) (*models.WebAuthnCredential, *types.Error) {
+	credential, err := s.findOwned(ctx, userID, passkeyID)
+	if err != nil {
+		err.Path = ".PasskeyService->RenamePasskey()" + err.Path
+		return nil, err
+	}
+
+	now := utils.Now()
+	credential.Name = name
+	credential.UpdatedAt = &now
+	credential, err = s.credentialStorage.Update(ctx, credential)
+	if err != nil {
+		err.Path = ".PasskeyService->RenamePasskey()" + err.Path
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// RemovePasskey removes a passkey of the user, it can't sign in anymore
+func (s *Service) RemovePasskey(ctx context.Context, userID int, passkeyID int, client *datatransfers.ClientInfo) *types.Error {
+	credential, err := s.findOwned(ctx, userID, passkeyID)
+	if err != nil {
+		err.Path = ".PasskeyService->RemovePasskey()" + err.Path
+		return err
+	}
+
+	err = s.credentialStorage.Delete(ctx, credential.ID)
+	if err != nil {
+		err.Path = ".PasskeyService->RemovePasskey()" + err.Path
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.EventPasskeyRemoved, &userID, client, types.Metadata{
+		"passkeyId": credential.ID,
+	})
+	if err != nil {
+		err.Path = ".PasskeyService->RemovePasskey()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// LoginOptions starts a passkey login, the options are passed to navigator.credentials.get().
+// They allow any discoverable credential, the user is known once the authenticator answers.
+func (s *Service) LoginOptions(ctx context.Context) (*webauthn.RequestOptions, *types.Error) {
+	challenge, errToken := utils.GenerateRandomToken(challengeSize)
+	if errToken != nil {
+		return nil, passkeyError(".PasskeyService->LoginOptions()", errToken)
+	}
+
+	errCache := redis.SetCache(ctx, fmt.Sprintf(constants.WebAuthnLoginCacheKey, challenge), 1, s.challengeTTL)
+	if errCache != nil {
+		return nil, passkeyError(".PasskeyService->LoginOptions()", errCache)
+	}
+
+	return s.relyingParty.RequestOptions(challenge, nil), nil
+}
+
+// VerifyLogin verifies the response to login options and returns the user the passkey belongs to.
+// A challenge answers one login, and a signature counter that didn't move forward rejects the login
+// and records a clone detection event.
+func (s *Service) VerifyLogin(ctx context.Context, response *webauthn.AssertionResponse, client *datatransfers.ClientInfo) (*models.User, *types.Error) {
+	challenge, errChallenge := response.Challenge()
+	if errChallenge != nil {
+		return nil, invalidPasskey(".PasskeyService->VerifyLogin()", errChallenge)
+	}
+	issued, errCache := redis.TakeCache(ctx, fmt.Sprintf(constants.WebAuthnLoginCacheKey, challenge))
+	if errCache != nil {
+		return nil, passkeyError(".PasskeyService->VerifyLogin()", errCache)
+	}
+	if issued == "" {
+		return nil, passkeyError(".PasskeyService->VerifyLogin()", types.ErrPasskeyChallengeExpired)
+	}
+
+	credentialID, errID := response.CredentialID()
+	if errID != nil {
+		return nil, invalidPasskey(".PasskeyService->VerifyLogin()", errID)
+	}
+	credential, err := s.credentialStorage.FindByCredentialID(ctx, credentialID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil, invalidPasskey(".PasskeyService->VerifyLogin()", err.Error)
+		}
+		err.Path = ".PasskeyService->VerifyLogin()" + err.Path
+		return nil, err
+	}
+
+	// Discoverable credentials report the user handle they were created with, it has to be the owner
+	handle := strings.TrimRight(response.Response.UserHandle, "=")
+	if handle != "" && handle != userHandle(credential.UserID) {
+		return nil, invalidPasskey(".PasskeyService->VerifyLogin()", errors.New("user handle doesn't match the credential"))
+	}
+
+	publicKey, errKey := base64.RawURLEncoding.DecodeString(credential.PublicKey)
+	if errKey != nil {
+		return nil, passkeyError(".PasskeyService->VerifyLogin()", errKey)
+	}
+
+	signCount, errVerify := s.relyingParty.VerifyAssertion(challenge, response, publicKey, uint32(credential.SignCount))
+	if errVerify == webauthn.ErrSignCountRollback {
+		return nil, s.cloneDetected(ctx, credential, client)
+	}
+	if errVerify != nil {
+		return nil, invalidPasskey(".PasskeyService->VerifyLogin()", errVerify)
+	}
+
+	// Two logins reporting the same counter can't both win, the loser is treated as a clone too
+	updated, err := s.credentialStorage.UpdateSignCount(ctx, credential.ID, credential.SignCount, int64(signCount))
+	if err != nil {
+		err.Path = ".PasskeyService->VerifyLogin()" + err.Path
+		return nil, err
+	}
+	if !updated {
+		return nil, s.cloneDetected(ctx, credential, client)
+	}
+
+	currentUser, err := s.userStorage.FindByID(ctx, credential.UserID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".PasskeyService->VerifyLogin()" + err.Path
+		return nil, err
+	}
+	if currentUser == nil || currentUser.DeletedAt != nil || !currentUser.IsActive {
+		return nil, invalidPasskey(".PasskeyService->VerifyLogin()", types.ErrInvalidCredentials)
+	}
+
+	return currentUser, nil
+}
+
+// cloneDetected records a passkey whose signature counter went backwards, the event is kept even though the login fails
+func (s *Service) cloneDetected(ctx context.Context, credential *models.WebAuthnCredential, client *datatransfers.ClientInfo) *types.Error {
+	err := s.auditService.RecordDetached(ctx, audit.EventPasskeyCloneDetected, &credential.UserID, client, types.Metadata{
+		"passkeyId": credential.ID,
+		"signCount": credential.SignCount,
+	})
+	if err != nil {
+		err.Path = ".PasskeyService->cloneDetected()" + err.Path
+		return err
+	}
+
+	return invalidPasskey(".PasskeyService->cloneDetected()", webauthn.ErrSignCountRollback)
+}
+
+// findOwned finds a passkey of the user, passkeys of other users are not found either
+func (s *Service) findOwned(ctx context.Context, userID int, passkeyID int) (*models.WebAuthnCredential, *types.Error) {
+	credential, err := s.credentialStorage.FindByID(ctx, passkeyID)
+	if err != nil {
+		err.Path = ".PasskeyService->findOwned()" + err.Path
+		return nil, err
+	}
+	if credential.UserID != userID || credential.DeletedAt != nil {
+		return nil, passkeyError(".PasskeyService->findOwned()", data.ErrNotFound)
+	}
+
+	return credential, nil
+}
+
+// userHandle is the WebAuthn user ID of a user, it carries no personal information
+func userHandle(userID int) string {
+	return webauthn.EncodeBase64URL([]byte(strconv.Itoa(userID)))
+}
+
+func descriptors(credentials []*models.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	result := make([]webauthn.CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		result[i] = webauthn.CredentialDescriptor{Type: "public-key", ID: credential.CredentialID}
+		if credential.Transports != "" {
+			result[i].Transports = strings.Split(credential.Transports, ",")
+		}
+	}
+	return result
+}
+
+// formatAAGUID formats the authenticator model ID as a UUID, all zeros for authenticators that don't tell
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", aaguid[0:4], aaguid[4:6], aaguid[6:8], aaguid[8:10], aaguid[10:16])
+}
+
+func passkeyError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// invalidPasskey keeps why the ceremony failed in the message, callers only see ErrPasskeyInvalid
+func invalidPasskey(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   types.ErrPasskeyInvalid,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewPasskeyService creates a new passkey Service, challenges expire with the timeout of the relying party
+func NewPasskeyService(
+	userStorage user.Storage,
+	credentialStorage webauthncredential.Storage,
+	auditService audit.ServiceInterface,
+	relyingParty *webauthn.RelyingParty,
+) *Service {
+	return &Service{
+		userStorage:       userStorage,
+		credentialStorage: credentialStorage,
+		auditService:      auditService,
+		relyingParty:      relyingParty,
+		challengeTTL:      time.Duration(relyingParty.Timeout) * time.Millisecond,
+	}
+}
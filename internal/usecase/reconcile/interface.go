@@ -0,0 +1,13 @@
+package reconcile
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the Clerk reconciliation service interface
+type ServiceInterface interface {
+	Reconcile(ctx context.Context, apply bool) (*datatransfers.ReconcileReport, *types.Error)
+}
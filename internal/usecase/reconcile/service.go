@@ -0,0 +1,264 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	userUsecase "github.com/riskibarqy/bq-account-service/internal/usecase/user"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Service is the domain logic implementation of Clerk reconciliation Service interface
+type Service struct {
+	dataManager *data.Manager
+	userStorage user.Storage
+	userService userUsecase.ServiceInterface
+	batchSize   int
+}
+
+// fix is the change that resolves a single report entry
+type fix struct {
+	entry *datatransfers.ReconcileEntry
+	apply func(ctx context.Context) *types.Error
+}
+
+// Reconcile compares every Clerk user with the user table by clerk_id.
+// Clerk users without a row are missing, rows whose Clerk user is gone are orphaned
+// and rows with a different name, email or username are mismatched. With apply
+// the drift is fixed through the same upsert and soft delete as the Clerk webhook,
+// one transaction per batch.
+func (s *Service) Reconcile(ctx context.Context, apply bool) (*datatransfers.ReconcileReport, *types.Error) {
+	report := &datatransfers.ReconcileReport{
+		DryRun:     !apply,
+		StartedAt:  utils.Now(),
+		Missing:    []*datatransfers.ReconcileEntry{},
+		Orphaned:   []*datatransfers.ReconcileEntry{},
+		Mismatched: []*datatransfers.ReconcileEntry{},
+		Errors:     []string{},
+	}
+	fixes := []*fix{}
+
+	clerkIDs, err := s.compareClerkUsers(ctx, report, &fixes)
+	if err != nil {
+		err.Path = ".ReconcileService->Reconcile()" + err.Path
+		return nil, err
+	}
+
+	err = s.findOrphanedUsers(ctx, report, &fixes, clerkIDs)
+	if err != nil {
+		err.Path = ".ReconcileService->Reconcile()" + err.Path
+		return nil, err
+	}
+
+	if apply {
+		s.applyFixes(ctx, report, fixes)
+	}
+
+	report.FinishedAt = utils.Now()
+	return report, nil
+}
+
+// compareClerkUsers pages through Clerk looking for missing and mismatched rows, it returns every Clerk ID seen
+func (s *Service) compareClerkUsers(ctx context.Context, report *datatransfers.ReconcileReport, fixes *[]*fix) (map[string]bool, *types.Error) {
+	clerkIDs := map[string]bool{}
+	limit := int64(s.batchSize)
+	offset := int64(0)
+
+	for {
+		page, errClerk := clerkUser.List(ctx, &clerkUser.ListParams{
+			ListParams: clerk.ListParams{Limit: &limit, Offset: &offset},
+		})
+		if errClerk != nil {
+			return nil, &types.Error{
+				Path:    ".ReconcileService->compareClerkUsers()",
+				Message: errClerk.Error(),
+				Error:   errClerk,
+				Type:    types.ErrTypesClerkError,
+			}
+		}
+
+		ids := make([]string, 0, len(page.Users))
+		for _, remote := range page.Users {
+			ids = append(ids, remote.ID)
+		}
+
+		locals, err := s.userStorage.FindByClerkIDs(ctx, ids)
+		if err != nil {
+			err.Path = ".ReconcileService->compareClerkUsers()" + err.Path
+			return nil, err
+		}
+		byClerkID := make(map[string]*models.User, len(locals))
+		for _, local := range locals {
+			byClerkID[local.ClerkID] = local
+		}
+
+		for _, remote := range page.Users {
+			clerkIDs[remote.ID] = true
+			report.ClerkUsers++
+
+			expected := userUsecase.FromIdentity(identity.FromClerkUser(remote))
+			local, found := byClerkID[remote.ID]
+			switch {
+			case !found:
+				entry := &datatransfers.ReconcileEntry{ClerkID: remote.ID, Email: expected.Email}
+				report.Missing = append(report.Missing, entry)
+				*fixes = append(*fixes, &fix{entry: entry, apply: s.syncUser(remote)})
+			case local.DeletedAt != nil:
+				// Deleted on our side, deleting the Clerk user is up to DeleteUser
+				continue
+			default:
+				changes := diffUser(local, expected)
+				if len(changes) == 0 {
+					continue
+				}
+
+				entry := &datatransfers.ReconcileEntry{ClerkID: remote.ID, UserID: local.ID, Email: local.Email, Changes: changes}
+				report.Mismatched = append(report.Mismatched, entry)
+				*fixes = append(*fixes, &fix{entry: entry, apply: s.syncUser(remote)})
+			}
+		}
+
+		offset += int64(len(page.Users))
+		if int64(len(page.Users)) < limit {
+			break
+		}
+	}
+
+	return clerkIDs, nil
+}
+
+// findOrphanedUsers pages through the active rows looking for Clerk IDs that Clerk no longer knows. The Clerk
+// listing can skip users that changed while it was paged and misses users created since, so rows created after
+// the run started are left alone and every other orphan is confirmed with Clerk before it is reported.
+func (s *Service) findOrphanedUsers(ctx context.Context, report *datatransfers.ReconcileReport, fixes *[]*fix, clerkIDs map[string]bool) *types.Error {
+	for page := 1; ; page++ {
+		locals, err := s.userStorage.FindAll(ctx, &datatransfers.FindAllParams{
+			Page:  page,
+			Limit: s.batchSize,
+		})
+		if err != nil {
+			err.Path = ".ReconcileService->findOrphanedUsers()" + err.Path
+			return err
+		}
+
+		for _, local := range locals {
+			// Users of other identity providers aren't in Clerk
+			if local.IdentityProvider != identity.ProviderClerk {
+				continue
+			}
+
+			report.LocalUsers++
+			if local.ClerkID == "" || clerkIDs[local.ClerkID] || local.CreatedAt >= report.StartedAt {
+				continue
+			}
+
+			clerkID := local.ClerkID
+			_, errClerk := clerkUser.Get(ctx, clerkID)
+			if errClerk == nil {
+				continue
+			}
+			if !isNotFound(errClerk) {
+				// Only a definite not found deletes a user
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: confirming orphan: %v", clerkID, errClerk))
+				continue
+			}
+
+			entry := &datatransfers.ReconcileEntry{ClerkID: clerkID, UserID: local.ID, Email: local.Email}
+			report.Orphaned = append(report.Orphaned, entry)
+			*fixes = append(*fixes, &fix{entry: entry, apply: func(ctx context.Context) *types.Error {
+				return s.userService.DeleteFromClerk(ctx, clerkID)
+			}})
+		}
+
+		if len(locals) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+// applyFixes applies the fixes one transaction per batch, a failed batch is rolled back and reported
+func (s *Service) applyFixes(ctx context.Context, report *datatransfers.ReconcileReport, fixes []*fix) {
+	for start := 0; start < len(fixes); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(fixes) {
+			end = len(fixes)
+		}
+		batch := fixes[start:end]
+
+		var failed *fix
+		var err *types.Error
+		errTransaction := s.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+			for _, f := range batch {
+				err = f.apply(ctx)
+				if err != nil {
+					failed = f
+					return err.Error
+				}
+			}
+			return nil
+		})
+		if errTransaction != nil {
+			if failed != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("batch %d-%d rolled back, %s: %s", start, end-1, failed.entry.ClerkID, err.Message))
+			} else {
+				report.Errors = append(report.Errors, fmt.Sprintf("batch %d-%d rolled back: %v", start, end-1, errTransaction))
+			}
+			continue
+		}
+
+		report.Applied += len(batch)
+	}
+}
+
+func isNotFound(err error) bool {
+	var apiErr *clerk.APIErrorResponse
+	return errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusNotFound
+}
+
+func (s *Service) syncUser(remote *clerk.User) func(ctx context.Context) *types.Error {
+	return func(ctx context.Context) *types.Error {
+		_, err := s.userService.SyncFromClerk(ctx, identity.FromClerkUser(remote))
+		return err
+	}
+}
+
+// diffUser lists the reconciled fields that differ, keyed by their JSON name
+func diffUser(local *models.User, expected *models.User) map[string]*datatransfers.ReconcileChange {
+	changes := map[string]*datatransfers.ReconcileChange{}
+	if local.Name != expected.Name {
+		changes["name"] = &datatransfers.ReconcileChange{Local: local.Name, Clerk: expected.Name}
+	}
+	if local.Email != expected.Email {
+		changes["email"] = &datatransfers.ReconcileChange{Local: local.Email, Clerk: expected.Email}
+	}
+	if local.Username != expected.Username {
+		changes["username"] = &datatransfers.ReconcileChange{Local: local.Username, Clerk: expected.Username}
+	}
+
+	return changes
+}
+
+// NewReconcileService creates a new Clerk reconciliation Service
+func NewReconcileService(
+	dataManager *data.Manager,
+	userStorage user.Storage,
+	userService userUsecase.ServiceInterface,
+	batchSize int,
+) *Service {
+	return &Service{
+		dataManager: dataManager,
+		userStorage: userStorage,
+		userService: userService,
+		batchSize:   batchSize,
+	}
+}
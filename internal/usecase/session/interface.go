@@ -0,0 +1,20 @@
+package session
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the session service interface
+type ServiceInterface interface {
+	CreateSession(ctx context.Context, userID int, authMethods []string, client *datatransfers.ClientInfo) (*models.Session, *types.Error)
+	GetSession(ctx context.Context, sessionID string) (*models.Session, *types.Error)
+	TouchSession(ctx context.Context, session *models.Session) *types.Error
+	ListSessions(ctx context.Context, userID int, currentSessionID string) ([]*models.Session, *types.Error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) *types.Error
+	RevokeOtherSessions(ctx context.Context, userID int, currentSessionID string) *types.Error
+	RevokeAllSessions(ctx context.Context, userID int) *types.Error
+}
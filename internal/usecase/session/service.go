@@ -0,0 +1,294 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// touchInterval limits how often the last-seen time of a session is written back
+const touchInterval = 60
+
+// Authentication methods a session can record, the amr values of RFC 8176
+const (
+	AuthMethodPassword    = "pwd"
+	AuthMethodOTP         = "otp"
+	AuthMethodMFA         = "mfa"
+	AuthMethodHardwareKey = "hwk"
+)
+
+// Service is the domain logic implementation of session Service interface
+type Service struct{}
+
+// CreateSession records a new login of the user and the methods it authenticated with
+func (s *Service) CreateSession(ctx context.Context, userID int, authMethods []string, client *datatransfers.ClientInfo) (*models.Session, *types.Error) {
+	sessionID, errRandom := utils.GenerateRandomToken(16)
+	if errRandom != nil {
+		return nil, &types.Error{
+			Path:    ".SessionService->CreateSession()",
+			Message: errRandom.Error(),
+			Error:   errRandom,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	now := utils.Now()
+	session := &models.Session{
+		ID:          sessionID,
+		UserID:      userID,
+		Device:      client.Device,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		AuthMethods: authMethods,
+	}
+
+	if err := s.save(ctx, session); err != nil {
+		err.Path = ".SessionService->CreateSession()" + err.Path
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetSession returns an active session, revoked and expired sessions are not found
+func (s *Service) GetSession(ctx context.Context, sessionID string) (*models.Session, *types.Error) {
+	cached, errCache := redis.GetCache(ctx, fmt.Sprintf(constants.SessionCacheKey, sessionID))
+	if errCache != nil {
+		return nil, &types.Error{
+			Path:    ".SessionService->GetSession()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+	if cached == "" {
+		return nil, &types.Error{
+			Path:    ".SessionService->GetSession()",
+			Message: types.ErrSessionNotFound.Error(),
+			Error:   types.ErrSessionNotFound,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	var session *models.Session
+	if err := jsoniter.Unmarshal([]byte(cached), &session); err != nil {
+		return nil, &types.Error{
+			Path:    ".SessionService->GetSession()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return session, nil
+}
+
+// TouchSession updates the last-seen time and extends the session lifetime. A session revoked since it was
+// read stays revoked, the touch only writes a session that still exists and never adds it back to the index.
+func (s *Service) TouchSession(ctx context.Context, session *models.Session) *types.Error {
+	now := utils.Now()
+	if now-session.LastSeenAt < touchInterval {
+		return nil
+	}
+
+	session.LastSeenAt = now
+	byteSession, _ := jsoniter.Marshal(session)
+	expiration := time.Duration(config.AppConfig.RefreshTokenTTL) * time.Second
+
+	touched, errCache := redis.SetCacheIfExists(ctx, fmt.Sprintf(constants.SessionCacheKey, session.ID), byteSession, expiration)
+	if errCache != nil {
+		return &types.Error{
+			Path:    ".SessionService->TouchSession()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+	if !touched {
+		return &types.Error{
+			Path:    ".SessionService->TouchSession()",
+			Message: types.ErrSessionNotFound.Error(),
+			Error:   types.ErrSessionNotFound,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	// The index lives as long as the newest session in it
+	errCache = redis.ExpireCache(ctx, fmt.Sprintf(constants.UserSessionsCacheKey, session.UserID), expiration)
+	if errCache != nil {
+		return &types.Error{
+			Path:    ".SessionService->TouchSession()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return nil
+}
+
+// ListSessions lists the active sessions of the user, most recently used first
+func (s *Service) ListSessions(ctx context.Context, userID int, currentSessionID string) ([]*models.Session, *types.Error) {
+	userSessionsKey := fmt.Sprintf(constants.UserSessionsCacheKey, userID)
+	sessionIDs, errCache := redis.GetSetMembers(ctx, userSessionsKey)
+	if errCache != nil {
+		return nil, &types.Error{
+			Path:    ".SessionService->ListSessions()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	sessions := []*models.Session{}
+	for _, sessionID := range sessionIDs {
+		session, err := s.GetSession(ctx, sessionID)
+		if err != nil {
+			if err.Error != types.ErrSessionNotFound {
+				err.Path = ".SessionService->ListSessions()" + err.Path
+				return nil, err
+			}
+
+			// The session expired on its own, drop it from the index
+			_ = redis.RemoveFromSet(ctx, userSessionsKey, sessionID)
+			continue
+		}
+
+		session.Current = session.ID == currentSessionID
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession revokes one session of the user
+func (s *Service) RevokeSession(ctx context.Context, userID int, sessionID string) *types.Error {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		err.Path = ".SessionService->RevokeSession()" + err.Path
+		return err
+	}
+
+	// Don't let a user probe or revoke sessions of somebody else
+	if session.UserID != userID {
+		return &types.Error{
+			Path:    ".SessionService->RevokeSession()",
+			Message: types.ErrSessionNotFound.Error(),
+			Error:   types.ErrSessionNotFound,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	if err := s.delete(ctx, userID, sessionID); err != nil {
+		err.Path = ".SessionService->RevokeSession()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except the current one
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID string) *types.Error {
+	sessionIDs, errCache := redis.GetSetMembers(ctx, fmt.Sprintf(constants.UserSessionsCacheKey, userID))
+	if errCache != nil {
+		return &types.Error{
+			Path:    ".SessionService->RevokeOtherSessions()",
+			Message: errCache.Error(),
+			Error:   errCache,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	for _, sessionID := range sessionIDs {
+		if sessionID == currentSessionID {
+			continue
+		}
+
+		if err := s.delete(ctx, userID, sessionID); err != nil {
+			err.Path = ".SessionService->RevokeOtherSessions()" + err.Path
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RevokeAllSessions revokes every session of the user
+func (s *Service) RevokeAllSessions(ctx context.Context, userID int) *types.Error {
+	err := s.RevokeOtherSessions(ctx, userID, "")
+	if err != nil {
+		err.Path = ".SessionService->RevokeAllSessions()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// save stores a new session for as long as a refresh token issued for it may live
+func (s *Service) save(ctx context.Context, session *models.Session) *types.Error {
+	byteSession, _ := jsoniter.Marshal(session)
+	expiration := time.Duration(config.AppConfig.RefreshTokenTTL) * time.Second
+
+	if err := redis.SetCache(ctx, fmt.Sprintf(constants.SessionCacheKey, session.ID), byteSession, expiration); err != nil {
+		return &types.Error{
+			Path:    ".SessionService->save()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	if err := redis.AddToSet(ctx, fmt.Sprintf(constants.UserSessionsCacheKey, session.UserID), expiration, session.ID); err != nil {
+		return &types.Error{
+			Path:    ".SessionService->save()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) delete(ctx context.Context, userID int, sessionID string) *types.Error {
+	if err := redis.DeleteCache(ctx, fmt.Sprintf(constants.SessionCacheKey, sessionID)); err != nil {
+		return &types.Error{
+			Path:    ".SessionService->delete()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	if err := redis.RemoveFromSet(ctx, fmt.Sprintf(constants.UserSessionsCacheKey, userID), sessionID); err != nil {
+		return &types.Error{
+			Path:    ".SessionService->delete()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return nil
+}
+
+// NewSessionService creates a new session Service
+func NewSessionService() *Service {
+	return &Service{}
+}
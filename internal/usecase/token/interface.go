@@ -0,0 +1,17 @@
+package token
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the token service interface
+type ServiceInterface interface {
+	IssueTokens(ctx context.Context, user *models.User, sessionID string) (*datatransfers.TokenResponse, *types.Error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*datatransfers.TokenResponse, *types.Error)
+	RevokeRefreshToken(ctx context.Context, refreshToken string) *types.Error
+	RevokeUserTokens(ctx context.Context, userID int) *types.Error
+}
@@ -0,0 +1,254 @@
+package token
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/repository/refreshtoken"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/session"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Service is the domain logic implementation of token Service interface
+type Service struct {
+	dataManager         *data.Manager
+	refreshTokenStorage refreshtoken.Storage
+	userStorage         user.Storage
+	sessionService      session.ServiceInterface
+}
+
+// IssueTokens issues an access token and starts a new refresh token family for a fresh login
+func (s *Service) IssueTokens(ctx context.Context, user *models.User, sessionID string) (*datatransfers.TokenResponse, *types.Error) {
+	familyID, errRandom := utils.GenerateRandomToken(16)
+	if errRandom != nil {
+		return nil, &types.Error{
+			Path:    ".TokenService->IssueTokens()",
+			Message: errRandom.Error(),
+			Error:   errRandom,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	tokens, err := s.issue(ctx, user, familyID, sessionID)
+	if err != nil {
+		err.Path = ".TokenService->IssueTokens()" + err.Path
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new access and refresh token pair.
+// Every refresh token can be used once, presenting an already rotated token
+// revokes its whole family since either the client or an attacker holds a stolen copy.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*datatransfers.TokenResponse, *types.Error) {
+	var result *datatransfers.TokenResponse
+	var err *types.Error
+	var reusedFamilyID string
+
+	errTransaction := s.dataManager.RunInTransaction(ctx, func(ctx context.Context) error {
+		current, errType := s.refreshTokenStorage.FindByTokenHash(ctx, utils.HashToken(refreshToken))
+		if errType != nil {
+			if errType.Error == data.ErrNotFound {
+				err = refreshTokenError(types.ErrRefreshTokenInvalid)
+				return err.Error
+			}
+			err = errType
+			return err.Error
+		}
+
+		if current.RevokedAt != nil {
+			err = refreshTokenError(types.ErrRefreshTokenInvalid)
+			return err.Error
+		}
+
+		if current.RotatedAt != nil {
+			reusedFamilyID = current.FamilyID
+			err = refreshTokenError(types.ErrRefreshTokenReused)
+			return err.Error
+		}
+
+		if current.ExpiresAt <= utils.Now() {
+			err = refreshTokenError(types.ErrRefreshTokenExpired)
+			return err.Error
+		}
+
+		// Revoking a session retires every refresh token issued for it
+		currentSession, errType := s.sessionService.GetSession(ctx, current.SessionID)
+		if errType != nil {
+			if errType.Error == types.ErrSessionNotFound {
+				err = refreshTokenError(types.ErrRefreshTokenInvalid)
+				return err.Error
+			}
+			err = errType
+			return err.Error
+		}
+		if currentSession.UserID != current.UserID {
+			err = refreshTokenError(types.ErrRefreshTokenInvalid)
+			return err.Error
+		}
+
+		// Losing this race means the same token was presented twice at once
+		rotated, errType := s.refreshTokenStorage.MarkRotated(ctx, current.ID)
+		if errType != nil {
+			err = errType
+			return err.Error
+		}
+		if !rotated {
+			reusedFamilyID = current.FamilyID
+			err = refreshTokenError(types.ErrRefreshTokenReused)
+			return err.Error
+		}
+
+		currentUser, errType := s.userStorage.FindByID(ctx, current.UserID)
+		if errType != nil {
+			err = errType
+			return err.Error
+		}
+		if currentUser.DeletedAt != nil || !currentUser.IsActive {
+			err = refreshTokenError(types.ErrRefreshTokenInvalid)
+			return err.Error
+		}
+
+		result, err = s.issue(ctx, currentUser, current.FamilyID, current.SessionID)
+		if err != nil {
+			return err.Error
+		}
+
+		err = s.sessionService.TouchSession(ctx, currentSession)
+		if err != nil {
+			// Revoked since it was read
+			if err.Error == types.ErrSessionNotFound {
+				err = refreshTokenError(types.ErrRefreshTokenInvalid)
+			}
+			return err.Error
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		// The transaction has been rolled back, so the family is revoked on its own
+		if reusedFamilyID != "" {
+			if errRevoke := s.refreshTokenStorage.RevokeFamily(ctx, reusedFamilyID); errRevoke != nil {
+				errRevoke.Path = ".TokenService->RefreshTokens()" + errRevoke.Path
+				errRevoke.Log(ctx, logger.Tracer)
+			}
+		}
+
+		if err == nil {
+			err = &types.Error{
+				Message: errTransaction.Error(),
+				Error:   errTransaction,
+				Type:    types.ErrTypesServiceError,
+			}
+		}
+		err.Path = ".TokenService->RefreshTokens()" + err.Path
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RevokeRefreshToken revokes the family of the given refresh token, unknown tokens are ignored
+func (s *Service) RevokeRefreshToken(ctx context.Context, refreshToken string) *types.Error {
+	current, err := s.refreshTokenStorage.FindByTokenHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".TokenService->RevokeRefreshToken()" + err.Path
+		return err
+	}
+
+	err = s.refreshTokenStorage.RevokeFamily(ctx, current.FamilyID)
+	if err != nil {
+		err.Path = ".TokenService->RevokeRefreshToken()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every refresh token of the user
+func (s *Service) RevokeUserTokens(ctx context.Context, userID int) *types.Error {
+	err := s.refreshTokenStorage.RevokeByUserID(ctx, userID)
+	if err != nil {
+		err.Path = ".TokenService->RevokeUserTokens()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// issue signs an access token and stores the hash of a new refresh token in the family
+func (s *Service) issue(ctx context.Context, user *models.User, familyID string, sessionID string) (*datatransfers.TokenResponse, *types.Error) {
+	accessToken, errToken := config.GenerateJWTToken(user, sessionID)
+	if errToken != nil {
+		return nil, &types.Error{
+			Path:    ".TokenService->issue()",
+			Message: errToken.Error(),
+			Error:   errToken,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	refreshToken, errRandom := utils.GenerateRandomToken(32)
+	if errRandom != nil {
+		return nil, &types.Error{
+			Path:    ".TokenService->issue()",
+			Message: errRandom.Error(),
+			Error:   errRandom,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	now := utils.Now()
+	_, err := s.refreshTokenStorage.Insert(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: now + config.AppConfig.RefreshTokenTTL,
+		CreatedAt: now,
+		UpdatedAt: &now,
+	})
+	if err != nil {
+		err.Path = ".TokenService->issue()" + err.Path
+		return nil, err
+	}
+
+	return &datatransfers.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    config.AppConfig.AccessTokenTTL,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func refreshTokenError(err error) *types.Error {
+	return &types.Error{
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewTokenService creates a new token Service
+func NewTokenService(
+	dataManager *data.Manager,
+	refreshTokenStorage refreshtoken.Storage,
+	userStorage user.Storage,
+	sessionService session.ServiceInterface,
+) *Service {
+	return &Service{
+		dataManager:         dataManager,
+		refreshTokenStorage: refreshTokenStorage,
+		userStorage:         userStorage,
+		sessionService:      sessionService,
+	}
+}
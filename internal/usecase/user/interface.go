@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
 	"github.com/riskibarqy/bq-account-service/internal/models"
 	"github.com/riskibarqy/bq-account-service/internal/types"
 )
@@ -11,11 +12,15 @@ import (
 // ServiceInterface represents the user service interface
 type ServiceInterface interface {
 	ListUsers(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.User, int, *types.Error)
-	// GetUser(ctx context.Context, userID int) (*models.User, *types.Error)
+	GetUser(ctx context.Context, userID int) (*models.User, *types.Error)
 	// CreateUser(ctx context.Context, params *datatransfers.RegisterUser) (*models.User, *types.Error)
 	Register(ctx context.Context, params *datatransfers.RegisterUser) (*models.User, *types.Error)
-	// UpdateUser(ctx context.Context, userID int, params *models.User) (*models.User, *types.Error)
-	// DeleteUser(ctx context.Context, userID int) *types.Error
+	UpdateUser(ctx context.Context, userID int, params *models.User) (*models.User, *types.Error)
+	DeleteUser(ctx context.Context, userID int) *types.Error
+	VerifyEmail(ctx context.Context, userID int, email string) (*models.User, *types.Error)
+	VerifyPhone(ctx context.Context, userID int, phone string) (*models.User, *types.Error)
+	SyncFromClerk(ctx context.Context, remote *identity.User) (*models.User, *types.Error)
+	DeleteFromClerk(ctx context.Context, clerkID string) *types.Error
 	// ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) *types.Error
 	// Login(ctx context.Context, email string, password string) (*datatransfers.LoginResponse, *types.Error)
 }
@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
-	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
 	"github.com/riskibarqy/bq-account-service/external/logger"
 	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
 	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
 	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/password"
+	"github.com/riskibarqy/bq-account-service/internal/phone"
+	"github.com/riskibarqy/bq-account-service/internal/repository/app"
 	"github.com/riskibarqy/bq-account-service/internal/repository/user"
 	"github.com/riskibarqy/bq-account-service/internal/types"
 	"github.com/riskibarqy/bq-account-service/utils"
@@ -21,13 +27,21 @@ import (
 
 // Service is the domain logic implementation of user Service interface
 type Service struct {
-	userStorage user.Storage
+	userStorage       user.Storage
+	appStorage        app.Storage
+	identityProviders identity.Providers
+	passwordPolicy    *password.Policy
 }
 
 func (s *Service) ListUsers(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.User, int, *types.Error) {
+	// Phones are stored in E.164, a phone that doesn't parse is looked up as is and matches nothing
+	if normalized, err := phone.Normalize(params.Phone, config.AppConfig.PhoneDefaultRegion); err == nil {
+		params.Phone = normalized
+	}
+
 	// Generate cache key
 	byteParams, _ := jsoniter.Marshal(params)
-	cacheKey := fmt.Sprintf("ListUsers-%s", utils.EncodeHexMD5(string(byteParams)))
+	cacheKey := constants.ListUsersCacheKeyPrefix + utils.EncodeHexMD5(string(byteParams))
 
 	// Try to get users from Redis cache
 	cached, count, errCache := redis.GetListCache(ctx, cacheKey)
@@ -65,43 +79,53 @@ func (s *Service) ListUsers(ctx context.Context, params *datatransfers.FindAllPa
 	return users, len(users), nil
 }
 
-// // GetUser is get user
-// func (s *Service) GetUser(ctx context.Context, userID int) (*models.User, *types.Error) {
-// 	cacheKey := fmt.Sprintf("GetUser-%d", userID)
-
-// 	// Try to get users from Redis cache
-// 	cached, errCache := redis.GetCache(ctx, cacheKey)
-// 	if errCache == nil && cached != "" {
-// 		// If cache hit, unmarshal the cached data into a slice of User models
-// 		var user *models.User
-// 		if err := jsoniter.Unmarshal([]byte(cached), &user); err == nil {
-// 			return user, nil
-// 		}
-// 	}
+// GetUser is get user
+func (s *Service) GetUser(ctx context.Context, userID int) (*models.User, *types.Error) {
+	cacheKey := fmt.Sprintf(constants.UserCacheKey, userID)
 
-// 	user, err := s.userStorage.FindByID(ctx, userID)
-// 	if err != nil {
-// 		err.Path = ".UserService->GetUser()" + err.Path
-// 		return nil, err
-// 	}
+	// Try to get users from Redis cache
+	cached, errCache := redis.GetCache(ctx, cacheKey)
+	if errCache == nil && cached != "" {
+		// If cache hit, unmarshal the cached data into a slice of User models
+		var user *models.User
+		if err := jsoniter.Unmarshal([]byte(cached), &user); err == nil {
+			return user, nil
+		}
+	}
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->GetUser()" + err.Path
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
 
-// 		// Cache user
-// 		byteResults, _ := jsoniter.Marshal(user)
-// 		expiration := time.Duration(config.MetadataConfig.RedisExpirationShort) * time.Second
+	go func() {
+		ctxChild := context.Background()
 
-// 		if err := redis.SetCache(ctxChild, cacheKey, byteResults, expiration); err != nil {
-// 			log.Printf("Failed to set user cache: %v", err)
-// 		}
-// 	}()
+		// Cache user
+		byteResults, _ := jsoniter.Marshal(user)
+		expiration := time.Duration(config.MetadataConfig.RedisExpirationShort) * time.Second
 
-// 	return user, nil
-// }
+		if err := redis.SetCache(ctxChild, cacheKey, byteResults, expiration); err != nil {
+			log.Printf("Failed to set user cache: %v", err)
+		}
+	}()
+
+	return user, nil
+}
 
 // Register create user
 func (s *Service) Register(ctx context.Context, params *datatransfers.RegisterUser) (*models.User, *types.Error) {
+	normalizedPhone, errType := normalizePhone(params.Phone)
+	if errType != nil {
+		errType.Path = ".UserService->Register()" + errType.Path
+		return nil, errType
+	}
+	params.Phone = normalizedPhone
+
 	users, _, errType := s.ListUsers(ctx, &datatransfers.FindAllParams{
 		Email: params.Email,
 		Phone: params.Phone,
@@ -119,32 +143,55 @@ func (s *Service) Register(ctx context.Context, params *datatransfers.RegisterUs
 		params.Username = utils.CreateUsernameFromEmail(params.Email)
 	}
 
-	clerkCreateResponse, errClerk := clerkUser.Create(ctx, &clerkUser.CreateParams{
-		EmailAddresses: &[]string{params.Email},
-		Username:       &params.Username,
-		Password:       &params.Password,
-		FirstName:      &f,
-		LastName:       &l,
+	violations := s.passwordPolicy.Check("password", params.Password, &password.PolicyUser{
+		Name:     params.Name,
+		Email:    params.Email,
+		Username: params.Username,
 	})
-	if errClerk != nil {
+	if violations != nil {
 		return nil, &types.Error{
 			Path:    ".UserService->Register()",
-			Message: errClerk.Error(),
-			Error:   errClerk,
-			Type:    "clerk-create-user",
+			Message: violations.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
 		}
 	}
 
+	providerName, errType := s.registrationProvider(ctx, params.AppID)
+	if errType != nil {
+		errType.Path = ".UserService->Register()" + errType.Path
+		return nil, errType
+	}
+	provider, errType := s.identityProviders.Get(providerName)
+	if errType != nil {
+		errType.Path = ".UserService->Register()" + errType.Path
+		return nil, errType
+	}
+
+	remote, errType := provider.CreateUser(ctx, &identity.CreateUserParams{
+		FirstName: f,
+		LastName:  l,
+		Username:  params.Username,
+		Email:     params.Email,
+		Password:  params.Password,
+	})
+	if errType != nil {
+		errType.Path = ".UserService->Register()" + errType.Path
+		return nil, errType
+	}
+
 	now := utils.Now()
 	userModel := &models.User{
-		ClerkID:   clerkCreateResponse.ID,
-		Name:      *clerkCreateResponse.FirstName + " " + *clerkCreateResponse.LastName,
+		ClerkID:   remote.ID,
+		Name:      remote.FirstName + " " + remote.LastName,
 		Email:     params.Email,
-		Username:  *clerkCreateResponse.Username,
+		Username:  remote.Username,
 		Phone:     params.Phone,
 		IsActive:  true,
 		CreatedAt: now,
 		UpdatedAt: &now,
+
+		IdentityProvider: providerName,
 	}
 
 	user, errType := s.userStorage.Insert(ctx, userModel)
@@ -152,13 +199,9 @@ func (s *Service) Register(ctx context.Context, params *datatransfers.RegisterUs
 		ctxTimeout, cancel := context.WithTimeout(ctx, 3*time.Second)
 		defer cancel()
 
-		if _, errClerkDeleteUser := clerkUser.Delete(ctxTimeout, clerkCreateResponse.ID); errClerkDeleteUser != nil {
-			(&types.Error{
-				Path:    ".UserService->Register()",
-				Message: errClerkDeleteUser.Error(),
-				Error:   errClerkDeleteUser,
-				Type:    "clerk-delete-user",
-			}).Log(ctx, logger.Tracer)
+		if errDelete := provider.DeleteUser(ctxTimeout, remote.ID); errDelete != nil {
+			errDelete.Path = ".UserService->Register()" + errDelete.Path
+			errDelete.Log(ctx, logger.Tracer)
 		}
 
 		errType.Path = ".UserService->Register()" + errType.Path
@@ -168,74 +211,333 @@ func (s *Service) Register(ctx context.Context, params *datatransfers.RegisterUs
 	return user, nil
 }
 
-// // UpdateUser update a user
-// func (s *Service) UpdateUser(ctx context.Context, userID int, params *models.User) (*models.User, *types.Error) {
-// 	user, err := s.GetUser(ctx, userID)
-// 	if err != nil {
-// 		err.Path = ".UserService->UpdateUser()" + err.Path
-// 		return nil, err
-// 	}
+// UpdateUser update the name, email, username and phone of a user.
+// An empty username or phone keeps the current value.
+func (s *Service) UpdateUser(ctx context.Context, userID int, params *models.User) (*models.User, *types.Error) {
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->UpdateUser()" + err.Path
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
 
-// 	users, _, err := s.ListUsers(ctx, &datatransfers.FindAllParams{
-// 		Email: params.Email,
-// 	})
-// 	if err != nil {
-// 		err.Path = ".UserService->UpdateUser()" + err.Path
-// 		return nil, err
-// 	}
-// 	if len(users) > 0 {
-// 		return nil, &types.Error{
-// 			Path:    ".UserService->CreateUser()",
-// 			Message: data.ErrAlreadyExist.Error(),
-// 			Error:   data.ErrAlreadyExist,
-// 			Type:    "validation-error",
-// 		}
-// 	}
+	if params.Username == "" {
+		params.Username = user.Username
+	}
+	if params.Phone == "" {
+		params.Phone = user.Phone
+	}
+	params.Phone, err = normalizePhone(params.Phone)
+	if err != nil {
+		err.Path = ".UserService->UpdateUser()" + err.Path
+		return nil, err
+	}
 
-// 	user.Name = params.Name
-// 	user.Email = params.Email
+	err = s.checkUniqueness(ctx, userID, params)
+	if err != nil {
+		err.Path = ".UserService->UpdateUser()" + err.Path
+		return nil, err
+	}
 
-// 	user, err = s.userStorage.Update(ctx, user)
-// 	if err != nil {
-// 		err.Path = ".UserService->UpdateUser()" + err.Path
-// 		return nil, err
-// 	}
+	provider, err := s.identityProviders.Get(user.IdentityProvider)
+	if err != nil {
+		err.Path = ".UserService->UpdateUser()" + err.Path
+		return nil, err
+	}
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	// The identity provider is updated first, like on Register, and reverted when the database write fails
+	changes, previous := profileChanges(user, params)
+	if user.ClerkID != "" && changes != nil {
+		_, err = provider.UpdateUser(ctx, user.ClerkID, changes)
+		if err != nil {
+			err.Path = ".UserService->UpdateUser()" + err.Path
+			return nil, err
+		}
+	}
 
-// 		cacheKey := fmt.Sprintf("GetUser-%d", userID)
+	// A new email or phone has to be verified again
+	if !strings.EqualFold(user.Email, params.Email) {
+		user.IsVerified = false
+	}
+	if user.Phone != params.Phone {
+		user.IsPhoneVerified = false
+	}
 
-// 		// delete user cache
-// 		if err := redis.DeleteCache(ctxChild, cacheKey); err != nil {
-// 			log.Printf("Failed to set user cache: %v", err)
-// 		}
-// 	}()
+	clerkID := user.ClerkID
+	user.Name = params.Name
+	user.Email = params.Email
+	user.Username = params.Username
+	user.Phone = params.Phone
+	now := utils.Now()
+	user.UpdatedAt = &now
 
-// 	return user, nil
-// }
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		if clerkID != "" && changes != nil {
+			ctxTimeout, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+			defer cancel()
+
+			if _, errRevert := provider.UpdateUser(ctxTimeout, clerkID, previous); errRevert != nil {
+				errRevert.Path = ".UserService->UpdateUser()" + errRevert.Path
+				errRevert.Log(ctx, logger.Tracer)
+			}
+		}
 
-// // DeleteUser delete a user
-// func (s *Service) DeleteUser(ctx context.Context, userID int) *types.Error {
-// 	err := s.userStorage.Delete(ctx, userID)
-// 	if err != nil {
-// 		err.Path = ".UserService->DeleteUser()" + err.Path
-// 		return err
-// 	}
+		err.Path = ".UserService->UpdateUser()" + err.Path
+		return nil, err
+	}
 
-// 	go func() {
-// 		ctxChild := context.Background()
+	s.invalidateUserCache(userID)
 
-// 		cacheKey := fmt.Sprintf("GetUser-%d", userID)
+	return user, nil
+}
 
-// 		// delete user cache
-// 		if err := redis.DeleteCache(ctxChild, cacheKey); err != nil {
-// 			log.Printf("Failed to set user cache: %v", err)
-// 		}
-// 	}()
+// VerifyEmail marks the email of the user as verified. The email is the one the verification
+// was sent to, a user who changed it since gets types.ErrTokenInvalid.
+func (s *Service) VerifyEmail(ctx context.Context, userID int, email string) (*models.User, *types.Error) {
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
+	if !strings.EqualFold(user.Email, email) {
+		return nil, &types.Error{
+			Path:    ".UserService->VerifyEmail()",
+			Message: types.ErrTokenInvalid.Error(),
+			Error:   types.ErrTokenInvalid,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+	if user.IsVerified {
+		return user, nil
+	}
 
-// 	return nil
-// }
+	now := utils.Now()
+	user.IsVerified = true
+	user.UpdatedAt = &now
+
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+
+	s.invalidateUserCache(userID)
+
+	return user, nil
+}
+
+// VerifyPhone marks the phone of the user as verified. The phone is the one the code
+// was sent to, a user who changed it since gets types.ErrOTPInvalid.
+func (s *Service) VerifyPhone(ctx context.Context, userID int, phone string) (*models.User, *types.Error) {
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->VerifyPhone()" + err.Path
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, types.NewError(data.ErrNotFound)
+	}
+	if user.Phone == "" || user.Phone != phone {
+		return nil, &types.Error{
+			Path:    ".UserService->VerifyPhone()",
+			Message: types.ErrOTPInvalid.Error(),
+			Error:   types.ErrOTPInvalid,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+	if user.IsPhoneVerified {
+		return user, nil
+	}
+
+	now := utils.Now()
+	user.IsPhoneVerified = true
+	user.UpdatedAt = &now
+
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->VerifyPhone()" + err.Path
+		return nil, err
+	}
+
+	s.invalidateUserCache(userID)
+
+	return user, nil
+}
+
+// DeleteUser soft deletes a user
+func (s *Service) DeleteUser(ctx context.Context, userID int) *types.Error {
+	user, err := s.userStorage.FindByID(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->DeleteUser()" + err.Path
+		return err
+	}
+	if user.DeletedAt != nil {
+		return types.NewError(data.ErrNotFound)
+	}
+
+	provider, err := s.identityProviders.Get(user.IdentityProvider)
+	if err != nil {
+		err.Path = ".UserService->DeleteUser()" + err.Path
+		return err
+	}
+
+	err = s.userStorage.Delete(ctx, userID)
+	if err != nil {
+		err.Path = ".UserService->DeleteUser()" + err.Path
+		return err
+	}
+
+	// A provider delete can't be undone, so it runs last and a failure rolls back
+	// the soft delete through the caller's transaction instead
+	if user.ClerkID != "" {
+		err = provider.DeleteUser(ctx, user.ClerkID)
+		if err != nil {
+			err.Path = ".UserService->DeleteUser()" + err.Path
+			return err
+		}
+	}
+
+	s.invalidateUserCache(userID)
+
+	return nil
+}
+
+// registrationProvider returns the identity provider of the app the user registers through,
+// registering without an app uses IDENTITY_PROVIDER
+func (s *Service) registrationProvider(ctx context.Context, appID int) (string, *types.Error) {
+	if appID == 0 {
+		return config.AppConfig.IdentityProvider, nil
+	}
+
+	app, err := s.appStorage.FindByID(ctx, appID)
+	if err != nil {
+		err.Path = ".UserService->registrationProvider()" + err.Path
+		return "", err
+	}
+	if app.DeletedAt != nil {
+		return "", types.NewError(data.ErrNotFound)
+	}
+
+	return app.IdentityProvider, nil
+}
+
+// profileChanges returns the identity provider update for the changed fields and the update that reverts it,
+// both are nil when nothing the provider knows about changed
+func profileChanges(current *models.User, updated *models.User) (*identity.UpdateUserParams, *identity.UpdateUserParams) {
+	changes := &identity.UpdateUserParams{}
+	previous := &identity.UpdateUserParams{}
+	changed := false
+
+	if updated.Name != current.Name {
+		f, l := utils.SplitName(updated.Name)
+		oldF, oldL := utils.SplitName(current.Name)
+		changes.FirstName, changes.LastName = &f, &l
+		previous.FirstName, previous.LastName = &oldF, &oldL
+		changed = true
+	}
+	if updated.Username != current.Username {
+		changes.Username, previous.Username = &updated.Username, &current.Username
+		changed = true
+	}
+	if updated.Email != current.Email {
+		changes.Email, previous.Email = &updated.Email, &current.Email
+		changed = true
+	}
+	if updated.Phone != current.Phone && updated.Phone != "" {
+		changes.Phone, previous.Phone = &updated.Phone, &current.Phone
+		changed = true
+	}
+
+	if !changed {
+		return nil, nil
+	}
+	return changes, previous
+}
+
+// normalizePhone normalizes the phone into E.164 with PHONE_DEFAULT_REGION, an empty phone stays empty
+func normalizePhone(raw string) (string, *types.Error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	normalized, errPhone := phone.Normalize(raw, config.AppConfig.PhoneDefaultRegion)
+	if errPhone != nil {
+		violations := types.FieldViolations{{Field: "phone", Message: "must be a valid phone number"}}
+		return "", &types.Error{
+			Path:    ".normalizePhone()",
+			Message: errPhone.Error(),
+			Error:   violations,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	return normalized, nil
+}
+
+// checkUniqueness makes sure no other user already has the email, phone or username.
+// It reads the database directly, a stale list cache must not let a duplicate through.
+func (s *Service) checkUniqueness(ctx context.Context, userID int, params *models.User) *types.Error {
+	checks := []struct {
+		value  string
+		filter *datatransfers.FindAllParams
+		field  func(*models.User) string
+		err    error
+	}{
+		{params.Email, &datatransfers.FindAllParams{Email: params.Email}, func(u *models.User) string { return u.Email }, types.ErrEmailAlreadyExists},
+		{params.Phone, &datatransfers.FindAllParams{Phone: params.Phone}, func(u *models.User) string { return u.Phone }, types.ErrPhoneAlreadyExists},
+		{params.Username, &datatransfers.FindAllParams{Username: params.Username}, func(u *models.User) string { return u.Username }, types.ErrUsernameAlreadyExists},
+	}
+
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+
+		users, err := s.userStorage.FindAll(ctx, check.filter)
+		if err != nil {
+			err.Path = ".UserService->checkUniqueness()" + err.Path
+			return err
+		}
+
+		// ILIKE treats "_" and "%" as wildcards, so only an exact match is a conflict
+		for _, user := range users {
+			if user.ID != userID && strings.EqualFold(check.field(user), check.value) {
+				return &types.Error{
+					Path:    ".UserService->checkUniqueness()",
+					Message: check.err.Error(),
+					Error:   check.err,
+					Type:    types.ErrTypesServiceError,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// invalidateUserCache drops the cached user and every cached user list
+func (s *Service) invalidateUserCache(userID int) {
+	go func() {
+		ctxChild := context.Background()
+
+		// delete user cache
+		if err := redis.DeleteCache(ctxChild, fmt.Sprintf(constants.UserCacheKey, userID)); err != nil {
+			log.Printf("Failed to delete user cache: %v", err)
+		}
+
+		for _, prefix := range []string{constants.ListUsersCacheKeyPrefix, "cnt-" + constants.ListUsersCacheKeyPrefix} {
+			if err := redis.DeleteCacheByPrefix(ctxChild, prefix); err != nil {
+				log.Printf("Failed to delete user list cache: %v", err)
+			}
+		}
+	}()
+}
 
 // // ChangePassword change password
 // func (s *Service) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) *types.Error {
@@ -347,8 +649,14 @@ func (s *Service) Register(ctx context.Context, params *datatransfers.RegisterUs
 // NewService creates a new user AppService
 func NewUserService(
 	userStorage user.Storage,
+	appStorage app.Storage,
+	identityProviders identity.Providers,
+	passwordPolicy *password.Policy,
 ) *Service {
 	return &Service{
-		userStorage: userStorage,
+		userStorage:       userStorage,
+		appStorage:        appStorage,
+		identityProviders: identityProviders,
+		passwordPolicy:    passwordPolicy,
 	}
 }
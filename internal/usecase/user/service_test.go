@@ -0,0 +1,254 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/external/logger"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/dto/datatransfers"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/password"
+	"github.com/riskibarqy/bq-account-service/internal/repository/user"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"go.opentelemetry.io/otel"
+)
+
+const testPassword = "Correct-Horse-9"
+
+var errInsert = errors.New("insert failed")
+
+// emptyCache is a Redis client that never has anything cached
+type emptyCache struct {
+	redis.CacheClient
+}
+
+func (c emptyCache) Get(ctx context.Context, key string) *goredis.StringCmd {
+	return goredis.NewStringResult("", goredis.Nil)
+}
+
+func (c emptyCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd {
+	return goredis.NewStatusResult("OK", nil)
+}
+
+func (c emptyCache) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	return goredis.NewIntResult(0, nil)
+}
+
+func (c emptyCache) Scan(ctx context.Context, cursor uint64, match string, count int64) *goredis.ScanCmd {
+	return goredis.NewScanCmdResult(nil, 0, nil)
+}
+
+// memoryStorage keeps users in memory, Insert fails while failInsert is set
+type memoryStorage struct {
+	user.Storage
+
+	mu         sync.Mutex
+	users      []*models.User
+	failInsert bool
+}
+
+func (s *memoryStorage) FindAll(ctx context.Context, params *datatransfers.FindAllParams) ([]*models.User, *types.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []*models.User{}
+	for _, u := range s.users {
+		if params.Email != "" && !strings.EqualFold(u.Email, params.Email) {
+			continue
+		}
+		if params.Phone != "" && u.Phone != params.Phone {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+func (s *memoryStorage) Insert(ctx context.Context, u *models.User) (*models.User, *types.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failInsert {
+		return nil, &types.Error{
+			Path:    ".memoryStorage->Insert()",
+			Message: errInsert.Error(),
+			Error:   errInsert,
+			Type:    types.ErrTypesServiceError,
+		}
+	}
+
+	u.ID = len(s.users) + 1
+	s.users = append(s.users, u)
+	return u, nil
+}
+
+func (s *memoryStorage) FindByClerkID(ctx context.Context, clerkID string) (*models.User, *types.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ClerkID == clerkID {
+			found := *u
+			return &found, nil
+		}
+	}
+
+	return nil, types.NewError(data.ErrNotFound)
+}
+
+func (s *memoryStorage) Update(ctx context.Context, u *models.User) (*models.User, *types.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.users {
+		if existing.ID == u.ID {
+			s.users[i] = u
+			return u, nil
+		}
+	}
+
+	return nil, types.NewError(data.ErrNotFound)
+}
+
+func TestMain(m *testing.M) {
+	redis.RedisClient = emptyCache{}
+	logger.Tracer = otel.Tracer("test")
+	config.AppConfig.IdentityProvider = identity.ProviderMemory
+	config.AppConfig.PhoneDefaultRegion = "ID"
+
+	os.Exit(m.Run())
+}
+
+func newTestService() (*Service, *memoryStorage, *identity.MemoryProvider) {
+	storage := &memoryStorage{}
+	provider := identity.NewMemoryProvider()
+	service := NewUserService(
+		storage,
+		nil,
+		identity.Providers{identity.ProviderMemory: provider},
+		&password.Policy{MinLength: 8},
+	)
+
+	return service, storage, provider
+}
+
+func registerParams(email string) *datatransfers.RegisterUser {
+	return &datatransfers.RegisterUser{
+		Name:     "Jane Doe",
+		Email:    email,
+		Phone:    "0812-3456-7890",
+		Password: testPassword,
+	}
+}
+
+func TestRegister(t *testing.T) {
+	service, storage, provider := newTestService()
+
+	registered, err := service.Register(context.Background(), registerParams("jane@example.com"))
+	if err != nil {
+		t.Fatalf("Register() error = %v", err.Error)
+	}
+
+	if registered.ID == 0 || len(storage.users) != 1 {
+		t.Fatalf("Register() stored %d users, want 1", len(storage.users))
+	}
+	if registered.IdentityProvider != identity.ProviderMemory {
+		t.Errorf("IdentityProvider = %q, want %q", registered.IdentityProvider, identity.ProviderMemory)
+	}
+	if registered.Phone != "+6281234567890" {
+		t.Errorf("Phone = %q, want it normalized to +6281234567890", registered.Phone)
+	}
+	if registered.Username == "" {
+		t.Error("Username is empty, want it derived from the email")
+	}
+	if !registered.IsActive {
+		t.Error("IsActive = false, want true")
+	}
+
+	remote, err := provider.GetUser(context.Background(), registered.ClerkID)
+	if err != nil {
+		t.Fatalf("provider has no user %q: %v", registered.ClerkID, err.Error)
+	}
+	if remote.Email != "jane@example.com" || remote.FirstName != "Jane" || remote.LastName != "Doe" {
+		t.Errorf("provider user = %+v, want Jane Doe <jane@example.com>", remote)
+	}
+	if _, err := provider.VerifyCredentials(context.Background(), "jane@example.com", testPassword); err != nil {
+		t.Errorf("VerifyCredentials() error = %v, want the registered password to work", err.Error)
+	}
+}
+
+func TestRegisterDuplicateEmail(t *testing.T) {
+	service, storage, provider := newTestService()
+
+	if _, err := service.Register(context.Background(), registerParams("jane@example.com")); err != nil {
+		t.Fatalf("first Register() error = %v", err.Error)
+	}
+
+	params := registerParams("jane@example.com")
+	params.Username = "jane2"
+	_, err := service.Register(context.Background(), params)
+	if err == nil || err.Error != types.ErrUserAlreadyExists {
+		t.Fatalf("Register() error = %v, want %v", err, types.ErrUserAlreadyExists)
+	}
+
+	if len(storage.users) != 1 {
+		t.Errorf("storage has %d users, want 1", len(storage.users))
+	}
+	if _, err := provider.VerifyCredentials(context.Background(), "jane2", testPassword); err == nil {
+		t.Error("the duplicate registration created a provider user")
+	}
+}
+
+func TestRegisterProviderFailure(t *testing.T) {
+	service, storage, provider := newTestService()
+
+	// The provider already knows the email, the local database doesn't
+	_, errCreate := provider.CreateUser(context.Background(), &identity.CreateUserParams{
+		Username: "someone",
+		Email:    "jane@example.com",
+		Password: testPassword,
+	})
+	if errCreate != nil {
+		t.Fatalf("CreateUser() error = %v", errCreate.Error)
+	}
+
+	_, err := service.Register(context.Background(), registerParams("jane@example.com"))
+	if err == nil || err.Error != types.ErrEmailAlreadyExists {
+		t.Fatalf("Register() error = %v, want %v", err, types.ErrEmailAlreadyExists)
+	}
+
+	if len(storage.users) != 0 {
+		t.Errorf("storage has %d users, want none after the provider failed", len(storage.users))
+	}
+}
+
+func TestRegisterRollsBackProviderUser(t *testing.T) {
+	service, storage, provider := newTestService()
+	storage.failInsert = true
+
+	_, err := service.Register(context.Background(), registerParams("jane@example.com"))
+	if err == nil || err.Error != errInsert {
+		t.Fatalf("Register() error = %v, want %v", err, errInsert)
+	}
+
+	if _, err := provider.VerifyCredentials(context.Background(), "jane@example.com", testPassword); err == nil {
+		t.Error("the provider user was not deleted after the insert failed")
+	}
+
+	// With the provider user gone the same email can register again
+	storage.failInsert = false
+	if _, err := service.Register(context.Background(), registerParams("jane@example.com")); err != nil {
+		t.Fatalf("Register() after rollback error = %v", err.Error)
+	}
+}
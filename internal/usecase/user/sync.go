@@ -0,0 +1,131 @@
+package user
+
+import (
+	"context"
+	"strings"
+
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/internal/data"
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/phone"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// SyncFromClerk upserts the user keyed on its Clerk ID from a Clerk webhook payload.
+// Applying the same payload twice is a no-op, and a soft deleted user is not
+// brought back by a late user.updated delivery. Webhooks aren't delivered in order,
+// a payload older than the one the row was synced from is ignored.
+func (s *Service) SyncFromClerk(ctx context.Context, remote *identity.User) (*models.User, *types.Error) {
+	params := FromIdentity(remote)
+
+	user, err := s.userStorage.FindByClerkID(ctx, remote.ID)
+	if err != nil && err.Error != data.ErrNotFound {
+		err.Path = ".UserService->SyncFromClerk()" + err.Path
+		return nil, err
+	}
+
+	now := utils.Now()
+	if user == nil {
+		params.IdentityProvider = identity.ProviderClerk
+		params.ClerkUpdatedAt = remote.UpdatedAt
+		params.IsActive = true
+		params.CreatedAt = now
+		params.UpdatedAt = &now
+
+		user, err = s.userStorage.Insert(ctx, params)
+		if err != nil {
+			err.Path = ".UserService->SyncFromClerk()" + err.Path
+			return nil, err
+		}
+
+		s.invalidateUserCache(user.ID)
+		return user, nil
+	}
+
+	if user.DeletedAt != nil || remote.UpdatedAt < user.ClerkUpdatedAt {
+		return user, nil
+	}
+
+	// Clerk users without a phone keep the one we already have
+	if params.Phone == "" {
+		params.Phone = user.Phone
+	}
+	// A newer payload with the same fields still moves the timestamp, or an older one delivered after it would apply
+	if params.Name == user.Name && params.Email == user.Email && params.Username == user.Username && params.Phone == user.Phone &&
+		remote.UpdatedAt == user.ClerkUpdatedAt {
+		return user, nil
+	}
+
+	// A new email or phone has to be verified again, unless Clerk already verified the email
+	if !strings.EqualFold(user.Email, params.Email) {
+		user.IsVerified = remote.EmailVerified
+	}
+	if user.Phone != params.Phone {
+		user.IsPhoneVerified = false
+	}
+
+	user.Name = params.Name
+	user.Email = params.Email
+	user.Username = params.Username
+	user.Phone = params.Phone
+	user.ClerkUpdatedAt = remote.UpdatedAt
+	user.UpdatedAt = &now
+
+	user, err = s.userStorage.Update(ctx, user)
+	if err != nil {
+		err.Path = ".UserService->SyncFromClerk()" + err.Path
+		return nil, err
+	}
+
+	s.invalidateUserCache(user.ID)
+	return user, nil
+}
+
+// DeleteFromClerk soft deletes the user with the given Clerk ID, unknown and already deleted users are ignored
+func (s *Service) DeleteFromClerk(ctx context.Context, clerkID string) *types.Error {
+	user, err := s.userStorage.FindByClerkID(ctx, clerkID)
+	if err != nil {
+		if err.Error == data.ErrNotFound {
+			return nil
+		}
+		err.Path = ".UserService->DeleteFromClerk()" + err.Path
+		return err
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+
+	err = s.userStorage.Delete(ctx, user.ID)
+	if err != nil {
+		err.Path = ".UserService->DeleteFromClerk()" + err.Path
+		return err
+	}
+
+	s.invalidateUserCache(user.ID)
+	return nil
+}
+
+// FromIdentity maps an identity provider user onto our user model
+func FromIdentity(remote *identity.User) *models.User {
+	user := &models.User{
+		ClerkID:  remote.ID,
+		Name:     strings.TrimSpace(remote.FirstName + " " + remote.LastName),
+		Email:    remote.Email,
+		Username: remote.Username,
+		Phone:    remote.Phone,
+	}
+	// Clerk keeps phones in E.164 already, a phone that still doesn't parse is kept as Clerk has it
+	if normalized, err := phone.Normalize(user.Phone, config.AppConfig.PhoneDefaultRegion); err == nil {
+		user.Phone = normalized
+	}
+	if user.Username == "" {
+		user.Username = utils.CreateUsernameFromEmail(user.Email)
+	}
+	if user.Name == "" {
+		user.Name = user.Username
+	}
+
+	return user
+}
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/riskibarqy/bq-account-service/internal/identity"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+)
+
+func TestSyncFromClerkEmailVerification(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		emailVerified bool
+		wantVerified  bool
+	}{
+		{name: "same email", email: "jane@example.com", wantVerified: true},
+		{name: "same email in another case", email: "Jane@Example.com", wantVerified: true},
+		{name: "new unverified email", email: "jane@another.com", wantVerified: false},
+		{name: "new email verified by Clerk", email: "jane@another.com", emailVerified: true, wantVerified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, storage, _ := newTestService()
+			storage.users = []*models.User{{
+				ID:              1,
+				ClerkID:         "user_1",
+				Name:            "Jane Doe",
+				Email:           "jane@example.com",
+				Username:        "jane",
+				Phone:           "+6281234567890",
+				IsActive:        true,
+				IsVerified:      true,
+				IsPhoneVerified: true,
+			}}
+
+			synced, err := service.SyncFromClerk(context.Background(), &identity.User{
+				ID:            "user_1",
+				FirstName:     "Jane",
+				LastName:      "Doe",
+				Username:      "jane",
+				Email:         tt.email,
+				EmailVerified: tt.emailVerified,
+			})
+			if err != nil {
+				t.Fatalf("SyncFromClerk() error = %v", err.Error)
+			}
+
+			if synced.Email != tt.email {
+				t.Errorf("Email = %q, want %q", synced.Email, tt.email)
+			}
+			if synced.IsVerified != tt.wantVerified {
+				t.Errorf("IsVerified = %v, want %v", synced.IsVerified, tt.wantVerified)
+			}
+			if !synced.IsPhoneVerified {
+				t.Error("IsPhoneVerified = false, want the unchanged phone to stay verified")
+			}
+		})
+	}
+}
+
+func TestSyncFromClerkPhoneChange(t *testing.T) {
+	service, storage, _ := newTestService()
+	storage.users = []*models.User{{
+		ID:              1,
+		ClerkID:         "user_1",
+		Name:            "Jane Doe",
+		Email:           "jane@example.com",
+		Username:        "jane",
+		Phone:           "+6281234567890",
+		IsActive:        true,
+		IsVerified:      true,
+		IsPhoneVerified: true,
+	}}
+
+	synced, err := service.SyncFromClerk(context.Background(), &identity.User{
+		ID:        "user_1",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Username:  "jane",
+		Email:     "jane@example.com",
+		Phone:     "+6281299998888",
+	})
+	if err != nil {
+		t.Fatalf("SyncFromClerk() error = %v", err.Error)
+	}
+
+	if synced.IsPhoneVerified {
+		t.Error("IsPhoneVerified = true, want a new phone to need verification")
+	}
+	if !synced.IsVerified {
+		t.Error("IsVerified = false, want the unchanged email to stay verified")
+	}
+}
+
+func TestSyncFromClerkIgnoresStaleEvents(t *testing.T) {
+	service, storage, _ := newTestService()
+	storage.users = []*models.User{{
+		ID:             1,
+		ClerkID:        "user_1",
+		Name:           "Jane Doe",
+		Email:          "jane@example.com",
+		Username:       "jane",
+		IsActive:       true,
+		ClerkUpdatedAt: 2000,
+	}}
+
+	stale, err := service.SyncFromClerk(context.Background(), &identity.User{
+		ID:        "user_1",
+		FirstName: "Old",
+		LastName:  "Name",
+		Username:  "jane",
+		Email:     "jane@example.com",
+		UpdatedAt: 1000,
+	})
+	if err != nil {
+		t.Fatalf("SyncFromClerk() error = %v", err.Error)
+	}
+	if stale.Name != "Jane Doe" || storage.users[0].Name != "Jane Doe" {
+		t.Errorf("Name = %q, want the older event ignored", storage.users[0].Name)
+	}
+
+	// A newer event without changes still moves the timestamp, so an event between the two is stale too
+	if _, err := service.SyncFromClerk(context.Background(), &identity.User{
+		ID:        "user_1",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Username:  "jane",
+		Email:     "jane@example.com",
+		UpdatedAt: 3000,
+	}); err != nil {
+		t.Fatalf("SyncFromClerk() error = %v", err.Error)
+	}
+	if storage.users[0].ClerkUpdatedAt != 3000 {
+		t.Errorf("ClerkUpdatedAt = %d, want 3000", storage.users[0].ClerkUpdatedAt)
+	}
+
+	synced, err := service.SyncFromClerk(context.Background(), &identity.User{
+		ID:        "user_1",
+		FirstName: "Janet",
+		LastName:  "Doe",
+		Username:  "jane",
+		Email:     "jane@example.com",
+		UpdatedAt: 2500,
+	})
+	if err != nil {
+		t.Fatalf("SyncFromClerk() error = %v", err.Error)
+	}
+	if synced.Name != "Jane Doe" {
+		t.Errorf("Name = %q, want the event older than the last sync ignored", synced.Name)
+	}
+}
@@ -0,0 +1,17 @@
+package verification
+
+import (
+	"context"
+
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+)
+
+// ServiceInterface represents the verification service interface
+type ServiceInterface interface {
+	SendEmailVerification(ctx context.Context, user *models.User) *types.Error
+	ResendEmailVerification(ctx context.Context, userID int) *types.Error
+	VerifyEmail(ctx context.Context, token string) (*models.User, *types.Error)
+	SendPhoneOTP(ctx context.Context, userID int) *types.Error
+	VerifyPhoneOTP(ctx context.Context, userID int, code string) (*models.User, *types.Error)
+}
@@ -0,0 +1,250 @@
+package verification
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/riskibarqy/bq-account-service/config"
+	"github.com/riskibarqy/bq-account-service/constants"
+	"github.com/riskibarqy/bq-account-service/external/mailer"
+	"github.com/riskibarqy/bq-account-service/external/redis"
+	"github.com/riskibarqy/bq-account-service/external/sms"
+	"github.com/riskibarqy/bq-account-service/internal/models"
+	"github.com/riskibarqy/bq-account-service/internal/types"
+	"github.com/riskibarqy/bq-account-service/internal/usecase/user"
+	"github.com/riskibarqy/bq-account-service/utils"
+)
+
+// Service is the domain logic implementation of verification Service interface
+type Service struct {
+	userService user.ServiceInterface
+	mailSender  mailer.Sender
+	smsSender   sms.Sender
+}
+
+// phoneOTP is the pending phone verification code of a user, as cached
+type phoneOTP struct {
+	CodeHash string `json:"codeHash"`
+	Phone    string `json:"phone"`
+}
+
+// phoneOTPDigits is the length of phone verification codes
+const phoneOTPDigits = 6
+
+// SendEmailVerification mails the user a link that verifies their current email
+func (s *Service) SendEmailVerification(ctx context.Context, user *models.User) *types.Error {
+	ttl := time.Duration(config.AppConfig.EmailVerificationTTL) * time.Second
+	token, errToken := config.GenerateEmailVerificationToken(user, ttl)
+	if errToken != nil {
+		return verificationError(".VerificationService->SendEmailVerification()", errToken)
+	}
+
+	link, errURL := url.Parse(config.AppConfig.EmailVerificationURL)
+	if errURL != nil {
+		return verificationError(".VerificationService->SendEmailVerification()", errURL)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	errSend := s.mailSender.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address, it expires in %s.\n\n%s\n\n"+
+			"If you didn't create an account you can ignore this email.\n", user.Name, ttl, link.String()),
+	})
+	if errSend != nil {
+		return verificationError(".VerificationService->SendEmailVerification()", errSend)
+	}
+
+	return nil
+}
+
+// ResendEmailVerification sends the verification email again, at most once per EMAIL_VERIFICATION_RESEND_INTERVAL
+func (s *Service) ResendEmailVerification(ctx context.Context, userID int) *types.Error {
+	currentUser, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".VerificationService->ResendEmailVerification()" + err.Path
+		return err
+	}
+	if currentUser.IsVerified {
+		return verificationError(".VerificationService->ResendEmailVerification()", types.ErrEmailAlreadyVerified)
+	}
+
+	interval := time.Duration(config.AppConfig.EmailVerificationResendInterval) * time.Second
+	allowed, errCache := redis.SetCacheIfNotExists(ctx, fmt.Sprintf(constants.EmailVerificationResendCacheKey, userID), 1, interval)
+	if errCache != nil {
+		return verificationError(".VerificationService->ResendEmailVerification()", errCache)
+	}
+	if !allowed {
+		return verificationError(".VerificationService->ResendEmailVerification()", types.ErrTooManyRequests)
+	}
+
+	err = s.SendEmailVerification(ctx, currentUser)
+	if err != nil {
+		err.Path = ".VerificationService->ResendEmailVerification()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// VerifyEmail verifies the email the token was issued for, an expired token gets types.ErrTokenExpired
+// and any other bad token types.ErrTokenInvalid
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*models.User, *types.Error) {
+	userID, email, errToken := config.ParseEmailVerificationToken(token)
+	if errToken != nil {
+		if errToken != types.ErrTokenExpired {
+			errToken = types.ErrTokenInvalid
+		}
+		return nil, verificationError(".VerificationService->VerifyEmail()", errToken)
+	}
+
+	verifiedUser, err := s.userService.VerifyEmail(ctx, userID, email)
+	if err != nil {
+		err.Path = ".VerificationService->VerifyEmail()" + err.Path
+		return nil, err
+	}
+
+	return verifiedUser, nil
+}
+
+// SendPhoneOTP texts a verification code to the phone of the user, at most once per PHONE_OTP_RESEND_INTERVAL.
+// A new code replaces the previous one and resets its attempts.
+func (s *Service) SendPhoneOTP(ctx context.Context, userID int) *types.Error {
+	currentUser, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		err.Path = ".VerificationService->SendPhoneOTP()" + err.Path
+		return err
+	}
+	if currentUser.Phone == "" {
+		return verificationError(".VerificationService->SendPhoneOTP()", types.ErrPhoneNotSet)
+	}
+	if currentUser.IsPhoneVerified {
+		return verificationError(".VerificationService->SendPhoneOTP()", types.ErrPhoneAlreadyVerified)
+	}
+
+	interval := time.Duration(config.AppConfig.PhoneOTPResendInterval) * time.Second
+	allowed, errCache := redis.SetCacheIfNotExists(ctx, fmt.Sprintf(constants.PhoneOTPResendCacheKey, userID), 1, interval)
+	if errCache != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errCache)
+	}
+	if !allowed {
+		return verificationError(".VerificationService->SendPhoneOTP()", types.ErrTooManyRequests)
+	}
+
+	code, errCode := utils.GenerateNumericCode(phoneOTPDigits)
+	if errCode != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errCode)
+	}
+
+	cached, errMarshal := jsoniter.Marshal(&phoneOTP{
+		CodeHash: hashPhoneOTP(userID, currentUser.Phone, code),
+		Phone:    currentUser.Phone,
+	})
+	if errMarshal != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errMarshal)
+	}
+
+	ttl := time.Duration(config.AppConfig.PhoneOTPTTL) * time.Second
+	if errCache := redis.SetCache(ctx, fmt.Sprintf(constants.PhoneOTPCacheKey, userID), cached, ttl); errCache != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errCache)
+	}
+	if errCache := redis.DeleteCache(ctx, fmt.Sprintf(constants.PhoneOTPAttemptsCacheKey, userID)); errCache != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errCache)
+	}
+
+	errSend := s.smsSender.Send(ctx, currentUser.Phone, fmt.Sprintf("Your verification code is %s, it expires in %s.", code, ttl))
+	if errSend != nil {
+		return verificationError(".VerificationService->SendPhoneOTP()", errSend)
+	}
+
+	return nil
+}
+
+// VerifyPhoneOTP verifies the phone of the user with the code texted to it. After PHONE_OTP_MAX_ATTEMPTS
+// wrong codes the code is dropped and a new one has to be requested.
+func (s *Service) VerifyPhoneOTP(ctx context.Context, userID int, code string) (*models.User, *types.Error) {
+	otpKey := fmt.Sprintf(constants.PhoneOTPCacheKey, userID)
+	attemptsKey := fmt.Sprintf(constants.PhoneOTPAttemptsCacheKey, userID)
+
+	cached, errCache := redis.GetCache(ctx, otpKey)
+	if errCache != nil {
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", errCache)
+	}
+	if cached == "" {
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", types.ErrOTPInvalid)
+	}
+
+	pending := &phoneOTP{}
+	if errUnmarshal := jsoniter.UnmarshalFromString(cached, pending); errUnmarshal != nil {
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", errUnmarshal)
+	}
+
+	// Counting before comparing keeps concurrent guesses from getting past the limit
+	ttl := time.Duration(config.AppConfig.PhoneOTPTTL) * time.Second
+	attempts, errCache := redis.Increment(ctx, attemptsKey, ttl)
+	if errCache != nil {
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", errCache)
+	}
+	if attempts > int64(config.AppConfig.PhoneOTPMaxAttempts) {
+		s.dropPhoneOTP(ctx, userID)
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", types.ErrOTPTooManyAttempts)
+	}
+
+	codeHash := hashPhoneOTP(userID, pending.Phone, code)
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(pending.CodeHash)) != 1 {
+		return nil, verificationError(".VerificationService->VerifyPhoneOTP()", types.ErrOTPInvalid)
+	}
+
+	verifiedUser, err := s.userService.VerifyPhone(ctx, userID, pending.Phone)
+	if err != nil {
+		err.Path = ".VerificationService->VerifyPhoneOTP()" + err.Path
+		return nil, err
+	}
+
+	s.dropPhoneOTP(ctx, userID)
+
+	return verifiedUser, nil
+}
+
+// dropPhoneOTP deletes the pending code and its attempts, a code left behind expires by itself
+func (s *Service) dropPhoneOTP(ctx context.Context, userID int) {
+	for _, key := range []string{constants.PhoneOTPCacheKey, constants.PhoneOTPAttemptsCacheKey} {
+		if err := redis.DeleteCache(ctx, fmt.Sprintf(key, userID)); err != nil {
+			log.Printf("Failed to delete phone OTP: %v", err)
+		}
+	}
+}
+
+// hashPhoneOTP binds the code to the user and phone it was sent for
+func hashPhoneOTP(userID int, phone string, code string) string {
+	return utils.HashToken(fmt.Sprintf("%d:%s:%s", userID, phone, code))
+}
+
+func verificationError(path string, err error) *types.Error {
+	return &types.Error{
+		Path:    path,
+		Message: err.Error(),
+		Error:   err,
+		Type:    types.ErrTypesServiceError,
+	}
+}
+
+// NewVerificationService creates a new verification Service
+func NewVerificationService(
+	userService user.ServiceInterface,
+	mailSender mailer.Sender,
+	smsSender sms.Sender,
+) *Service {
+	return &Service{
+		userService: userService,
+		mailSender:  mailSender,
+		smsSender:   smsSender,
+	}
+}
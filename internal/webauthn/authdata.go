@@ -0,0 +1,85 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformedAuthenticatorData = errors.New("malformed authenticator data")
+
+// Authenticator data flags (WebAuthn level 3 section 6.1)
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackedUp               byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+// AuthenticatorData is the parsed authenticatorData of a registration or an assertion
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Attested credential data, only present when registering
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+// parseAuthenticatorData parses rpIdHash (32) | flags (1) | signCount (4) | attested credential data | extensions
+func parseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrMalformedAuthenticatorData
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&FlagAttestedCredentialData != 0 {
+		// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey
+		if len(rest) < 18 {
+			return nil, ErrMalformedAuthenticatorData
+		}
+		authData.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, ErrMalformedAuthenticatorData
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The key is the one CBOR item whose length isn't given up front
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrMalformedAuthenticatorData
+		}
+		authData.CredentialPublicKey = rest[:len(rest)-len(afterKey)]
+		rest = afterKey
+	}
+
+	if authData.Flags&FlagExtensionData != 0 {
+		_, afterExtensions, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrMalformedAuthenticatorData
+		}
+		rest = afterExtensions
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrMalformedAuthenticatorData
+	}
+
+	return authData, nil
+}
+
+func (a *AuthenticatorData) has(flag byte) bool {
+	return a.Flags&flag != 0
+}
@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want interface{}
+	}{
+		{"small integer", []byte{0x17}, int64(23)},
+		{"one byte integer", []byte{0x18, 0xff}, int64(255)},
+		{"negative integer", []byte{0x38, 0x63}, int64(-100)},
+		{"byte string", []byte{0x43, 0x01, 0x02, 0x03}, []byte{1, 2, 3}},
+		{"text", []byte{0x63, 'f', 'm', 't'}, "fmt"},
+		{"array", []byte{0x82, 0x01, 0x20}, []interface{}{int64(1), int64(-1)}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0xf5}, map[interface{}]interface{}{int64(1): int64(2), "a": true}},
+		{"tag", []byte{0xc1, 0x1a, 0x00, 0x01, 0x00, 0x00}, int64(65536)},
+		{"half float", []byte{0xf9, 0x3c, 0x00}, float64(1)},
+		{"null", []byte{0xf6}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(append(tt.data, 0xff))
+			if err != nil {
+				t.Fatalf("decodeCBOR() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decodeCBOR() = %#v, want %#v", got, tt.want)
+			}
+			if len(rest) != 1 || rest[0] != 0xff {
+				t.Fatalf("decodeCBOR() rest = %x, want ff", rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	deep := []byte{}
+	for i := 0; i <= maxCBORDepth+1; i++ {
+		deep = append(deep, 0x81)
+	}
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated byte string", []byte{0x45, 0x01, 0x02}},
+		{"truncated text", []byte{0x7a, 0x00, 0x00, 0x00, 0x10, 'a'}},
+		{"truncated array", []byte{0x83, 0x01, 0x02}},
+		{"truncated map", []byte{0xa2, 0x01, 0x02, 0x03}},
+		{"map key without value", []byte{0xa1, 0x01}},
+		{"huge array count", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge map count", []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge byte string", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"indefinite length", []byte{0x9f, 0x01, 0xff}},
+		{"reserved additional information", []byte{0x1c}},
+		{"array map key", []byte{0xa1, 0x80, 0x01}},
+		{"truncated float", []byte{0xfb, 0x00, 0x00}},
+		{"unknown simple value", []byte{0xf8, 0x20}},
+		{"too deep", deep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); !errors.Is(err, errCBOR) {
+				t.Fatalf("decodeCBOR() error = %v, want %v", err, errCBOR)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORTruncatedNeverPanics(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+	registration := authenticator.register(testRPID, testOrigin, testChallenge)
+	attestationObject, err := decodeBase64URL(registration.Response.AttestationObject)
+	if err != nil {
+		t.Fatalf("decode attestation object: %v", err)
+	}
+
+	for i := 0; i < len(attestationObject); i++ {
+		truncated := attestationObject[:i]
+		if _, _, err := decodeCBOR(truncated); err == nil {
+			t.Fatalf("decodeCBOR() of %d of %d bytes succeeded", i, len(attestationObject))
+		}
+
+		response := *registration
+		response.Response.AttestationObject = EncodeBase64URL(truncated)
+		if _, err := testRP.VerifyRegistration(testChallenge, &response); err == nil {
+			t.Fatalf("VerifyRegistration() of %d of %d bytes succeeded", i, len(attestationObject))
+		}
+	}
+}
+
+func TestParseAuthenticatorDataMalformed(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+	authData := authenticator.authenticatorData(testRPID, true)
+
+	for i := 0; i < len(authData); i++ {
+		if _, err := parseAuthenticatorData(authData[:i]); !errors.Is(err, ErrMalformedAuthenticatorData) {
+			t.Fatalf("parseAuthenticatorData() of %d of %d bytes error = %v, want %v", i, len(authData), err, ErrMalformedAuthenticatorData)
+		}
+	}
+
+	if _, err := parseAuthenticatorData(append(authData, 0x00)); !errors.Is(err, ErrMalformedAuthenticatorData) {
+		t.Fatalf("parseAuthenticatorData() with trailing bytes error = %v, want %v", err, ErrMalformedAuthenticatorData)
+	}
+}
+
+func TestParsePublicKeyMalformed(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+	coseKey := authenticator.coseKey()
+
+	for i := 0; i < len(coseKey); i++ {
+		if _, err := parsePublicKey(coseKey[:i]); !errors.Is(err, ErrUnsupportedKey) {
+			t.Fatalf("parsePublicKey() of %d of %d bytes error = %v, want %v", i, len(coseKey), err, ErrUnsupportedKey)
+		}
+	}
+
+	offCurve := encodeCBOR(map[interface{}]interface{}{
+		coseKeyType:      coseKeyTypeEC2,
+		coseKeyAlgorithm: AlgES256,
+		coseKeyCurve:     coseCurveP256,
+		coseKeyX:         make([]byte, 32),
+		coseKeyY:         make([]byte, 32),
+	})
+	if _, err := parsePublicKey(offCurve); !errors.Is(err, ErrUnsupportedKey) {
+		t.Fatalf("parsePublicKey() of a point off the curve error = %v, want %v", err, ErrUnsupportedKey)
+	}
+}
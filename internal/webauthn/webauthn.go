@@ -0,0 +1,405 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrMalformedResponse      = errors.New("malformed credential response")
+	ErrClientData             = errors.New("client data doesn't match the ceremony")
+	ErrRPID                   = errors.New("credential is scoped to another relying party")
+	ErrUserNotPresent         = errors.New("user presence was not confirmed")
+	ErrUserNotVerified        = errors.New("user verification was not performed")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrAttestation            = errors.New("attestation statement does not verify")
+	ErrSignCountRollback      = errors.New("signature counter went backwards, the authenticator may be cloned")
+)
+
+// Client data types of the two ceremonies
+const (
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+)
+
+// RelyingParty is this service as a WebAuthn relying party. ID is the registrable domain the
+// credentials are scoped to and Origins the exact origins the ceremonies may run on.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+
+	// Timeout is how long the browser gives the user, in milliseconds
+	Timeout int
+}
+
+// CredentialDescriptor identifies a credential in options, IDs are base64url without padding
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions, the argument of navigator.credentials.create()
+type CreationOptions struct {
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RPEntity describes the relying party to the authenticator
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity describes the account a credential is created for, ID is the base64url user handle
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter is an acceptable credential type and algorithm
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// AuthenticatorSelection states what kind of authenticator the relying party wants
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions, the argument of navigator.credentials.get()
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int                    `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential navigator.credentials.create() resolves to,
+// binary fields are base64url
+type RegistrationResponse struct {
+	ID       string `json:"id" validate:"required"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" validate:"required"`
+		AttestationObject string   `json:"attestationObject" validate:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential navigator.credentials.get() resolves to,
+// binary fields are base64url
+type AssertionResponse struct {
+	ID       string `json:"id" validate:"required"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" validate:"required"`
+		AuthenticatorData string `json:"authenticatorData" validate:"required"`
+		Signature         string `json:"signature" validate:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a verified new credential, what has to be stored to verify its assertions
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	BackupEligible bool
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Challenge returns the challenge the browser signed over, so the ceremony it answers can be looked up.
+// It is only trustworthy once VerifyAssertion passes.
+func (r *AssertionResponse) Challenge() (string, error) {
+	clientDataJSON, err := decodeBase64URL(r.Response.ClientDataJSON)
+	if err != nil {
+		return "", ErrMalformedResponse
+	}
+
+	data := &clientData{}
+	if err := json.Unmarshal(clientDataJSON, data); err != nil || data.Challenge == "" {
+		return "", ErrMalformedResponse
+	}
+	return strings.TrimRight(data.Challenge, "="), nil
+}
+
+// CredentialID returns the ID of the credential that signed, in canonical base64url
+func (r *AssertionResponse) CredentialID() (string, error) {
+	id, err := decodeBase64URL(r.ID)
+	if err != nil || len(id) == 0 {
+		return "", ErrMalformedResponse
+	}
+	return EncodeBase64URL(id), nil
+}
+
+// CreationOptions builds the registration options, excluding the credentials the user already has
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, algorithm := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: "public-key", Alg: algorithm}
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		RP:                 RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			// Passkeys are discoverable, so the user doesn't have to type who they are
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the login options, an empty allow list lets the user pick any discoverable credential
+func (rp *RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout,
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration runs the registration ceremony checks of WebAuthn level 3 section 7.1 on the
+// response to options created with the given challenge, and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge string, response *RegistrationResponse) (*Credential, error) {
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrMalformedResponse
+	}
+	if err := rp.verifyClientData(clientDataJSON, typeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := decodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrMalformedResponse
+	}
+	decoded, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrMalformedResponse
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrMalformedResponse
+	}
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if statement == nil || rawAuthData == nil {
+		return nil, ErrMalformedResponse
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if !authData.has(FlagAttestedCredentialData) {
+		return nil, ErrMalformedResponse
+	}
+
+	// The credential ID the client reports has to be the one the authenticator attested
+	if id, err := decodeBase64URL(response.ID); err != nil || !bytes.Equal(id, authData.CredentialID) {
+		return nil, ErrMalformedResponse
+	}
+
+	credentialKey, err := parsePublicKey(authData.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := verifyAttestation(format, statement, rawAuthData, clientDataHash[:], credentialKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             authData.CredentialID,
+		PublicKey:      authData.CredentialPublicKey,
+		SignCount:      authData.SignCount,
+		AAGUID:         authData.AAGUID,
+		Transports:     response.Response.Transports,
+		BackupEligible: authData.has(FlagBackupEligible),
+	}, nil
+}
+
+// VerifyAssertion runs the authentication ceremony checks of WebAuthn level 3 section 7.2 on the response
+// to options created with the given challenge, against the stored public key and signature counter of the
+// credential, and returns the new counter. A counter that didn't move forward gets ErrSignCountRollback,
+// authenticators that don't count always report 0.
+func (rp *RelyingParty) VerifyAssertion(challenge string, response *AssertionResponse, storedPublicKey []byte, storedSignCount uint32) (uint32, error) {
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return 0, ErrMalformedResponse
+	}
+	if err := rp.verifyClientData(clientDataJSON, typeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrMalformedResponse
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	signature, err := decodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return 0, ErrMalformedResponse
+	}
+	credentialKey, err := parsePublicKey(storedPublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := credentialKey.verify(append(append([]byte{}, rawAuthData...), clientDataHash[:]...), signature); err != nil {
+		return 0, err
+	}
+
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, ErrSignCountRollback
+	}
+
+	return authData.SignCount, nil
+}
+
+// verifyClientData checks the type, challenge and origin the browser signed over
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge string) error {
+	data := &clientData{}
+	if err := json.Unmarshal(clientDataJSON, data); err != nil {
+		return ErrMalformedResponse
+	}
+
+	if data.Type != ceremony || challenge == "" ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge)) != 1 {
+		return ErrClientData
+	}
+
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrClientData
+}
+
+// verifyAuthenticatorData checks the relying party scope and that the user was there and verified
+func (rp *RelyingParty) verifyAuthenticatorData(authData *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return ErrRPID
+	}
+	if !authData.has(FlagUserPresent) {
+		return ErrUserNotPresent
+	}
+	if !authData.has(FlagUserVerified) {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// verifyAttestation checks the attestation statement. We ask for no attestation, so "none" is what
+// browsers send; "packed" is checked for its signature only, attestation certificates aren't trusted
+// against any root since nothing is decided on the authenticator model.
+func verifyAttestation(format string, statement map[interface{}]interface{}, rawAuthData []byte, clientDataHash []byte, credentialKey *publicKey) error {
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return ErrAttestation
+		}
+		return nil
+
+	case "packed":
+		algorithm, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+		if signature == nil {
+			return ErrAttestation
+		}
+
+		signer := credentialKey
+		if chain, ok := statement["x5c"].([]interface{}); ok {
+			if len(chain) == 0 {
+				return ErrAttestation
+			}
+			leaf, _ := chain[0].([]byte)
+			if leaf == nil {
+				return ErrAttestation
+			}
+			var err error
+			signer, err = certificatePublicKey(leaf, algorithm)
+			if err != nil {
+				return ErrAttestation
+			}
+		} else if algorithm != credentialKey.algorithm {
+			// Self attestation is signed by the credential key itself
+			return ErrAttestation
+		}
+
+		signed := append(append([]byte{}, rawAuthData...), clientDataHash...)
+		if err := signer.verify(signed, signature); err != nil {
+			return ErrAttestation
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAttestation
+}
+
+// EncodeBase64URL encodes binary WebAuthn fields the way the JSON forms carry them
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeBase64URL decodes base64url with or without padding, clients differ
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,306 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+const (
+	testRPID      = "example.com"
+	testOrigin    = "https://example.com"
+	testChallenge = "c2VjcmV0LWNoYWxsZW5nZQ"
+)
+
+var testRP = &RelyingParty{ID: testRPID, Name: "Example", Origins: []string{testOrigin}}
+
+// softwareAuthenticator is an ES256 authenticator held in memory, standing in for a browser and a security key
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	flags        byte
+
+	// counterless authenticators always report a signature counter of 0
+	counterless bool
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential id: %v", err)
+	}
+
+	return &softwareAuthenticator{
+		key:          key,
+		credentialID: credentialID,
+		flags:        FlagUserPresent | FlagUserVerified,
+	}
+}
+
+// coseKey encodes the public key as a COSE_Key
+func (a *softwareAuthenticator) coseKey() []byte {
+	return encodeCBOR(map[interface{}]interface{}{
+		coseKeyType:      coseKeyTypeEC2,
+		coseKeyAlgorithm: AlgES256,
+		coseKeyCurve:     coseCurveP256,
+		coseKeyX:         a.key.X.FillBytes(make([]byte, 32)),
+		coseKeyY:         a.key.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+// authenticatorData builds the authenticator data for the relying party, with the attested
+// credential data when registering
+func (a *softwareAuthenticator) authenticatorData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := a.flags
+	if attested {
+		flags |= FlagAttestedCredentialData
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+// register answers navigator.credentials.create() with a "none" attestation
+func (a *softwareAuthenticator) register(rpID string, origin string, challenge string) *RegistrationResponse {
+	response := &RegistrationResponse{ID: EncodeBase64URL(a.credentialID), Type: "public-key"}
+	response.Response.ClientDataJSON = EncodeBase64URL(clientDataJSON(typeCreate, challenge, origin))
+	response.Response.AttestationObject = EncodeBase64URL(encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authenticatorData(rpID, true),
+	}))
+	return response
+}
+
+// assert answers navigator.credentials.get(), moving the signature counter forward
+func (a *softwareAuthenticator) assert(t *testing.T, rpID string, origin string, challenge string) *AssertionResponse {
+	t.Helper()
+
+	if !a.counterless {
+		a.signCount++
+	}
+	rawClientData := clientDataJSON(typeGet, challenge, origin)
+	authData := a.authenticatorData(rpID, false)
+	clientDataHash := sha256.Sum256(rawClientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	response := &AssertionResponse{ID: EncodeBase64URL(a.credentialID), Type: "public-key"}
+	response.Response.ClientDataJSON = EncodeBase64URL(rawClientData)
+	response.Response.AuthenticatorData = EncodeBase64URL(authData)
+	response.Response.Signature = EncodeBase64URL(signature)
+	return response
+}
+
+func clientDataJSON(ceremony string, challenge string, origin string) []byte {
+	data, _ := json.Marshal(&clientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	return data
+}
+
+// encodeCBOR encodes the subset of CBOR decodeCBOR reads, map keys are sorted so the output is stable
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case int:
+		return encodeCBOR(int64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		encoded := map[string][]byte{}
+		for key, item := range v {
+			k := encodeCBOR(key)
+			keys = append(keys, k)
+			encoded[string(k)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+
+		out := cborHead(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, k...)
+			out = append(out, encoded[string(k)]...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+func cborHead(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	case argument <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, argument)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+
+	credential, err := testRP.VerifyRegistration(testChallenge, authenticator.register(testRPID, testOrigin, testChallenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if string(credential.ID) != string(authenticator.credentialID) {
+		t.Fatalf("credential id = %x, want %x", credential.ID, authenticator.credentialID)
+	}
+
+	signCount := credential.SignCount
+	for i := 0; i < 2; i++ {
+		response := authenticator.assert(t, testRPID, testOrigin, testChallenge)
+
+		challenge, err := response.Challenge()
+		if err != nil || challenge != testChallenge {
+			t.Fatalf("Challenge() = %q, %v, want %q", challenge, err, testChallenge)
+		}
+
+		signCount, err = testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, signCount)
+		if err != nil {
+			t.Fatalf("VerifyAssertion: %v", err)
+		}
+		if signCount != authenticator.signCount {
+			t.Fatalf("sign count = %d, want %d", signCount, authenticator.signCount)
+		}
+	}
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+
+	tests := []struct {
+		name     string
+		response *RegistrationResponse
+		want     error
+	}{
+		{"wrong origin", authenticator.register(testRPID, "https://evil.example", testChallenge), ErrClientData},
+		{"wrong challenge", authenticator.register(testRPID, testOrigin, "b3RoZXItY2hhbGxlbmdl"), ErrClientData},
+		{"wrong relying party", authenticator.register("evil.example", testOrigin, testChallenge), ErrRPID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testRP.VerifyRegistration(testChallenge, tt.response); !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyRegistration() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("assertion instead of registration", func(t *testing.T) {
+		assertion := authenticator.assert(t, testRPID, testOrigin, testChallenge)
+		response := authenticator.register(testRPID, testOrigin, testChallenge)
+		response.Response.ClientDataJSON = assertion.Response.ClientDataJSON
+		if _, err := testRP.VerifyRegistration(testChallenge, response); !errors.Is(err, ErrClientData) {
+			t.Fatalf("VerifyRegistration() error = %v, want %v", err, ErrClientData)
+		}
+	})
+
+	t.Run("user not verified", func(t *testing.T) {
+		unverified := newSoftwareAuthenticator(t)
+		unverified.flags = FlagUserPresent
+		response := unverified.register(testRPID, testOrigin, testChallenge)
+		if _, err := testRP.VerifyRegistration(testChallenge, response); !errors.Is(err, ErrUserNotVerified) {
+			t.Fatalf("VerifyRegistration() error = %v, want %v", err, ErrUserNotVerified)
+		}
+	})
+}
+
+func TestAssertionRejected(t *testing.T) {
+	authenticator := newSoftwareAuthenticator(t)
+	credential, err := testRP.VerifyRegistration(testChallenge, authenticator.register(testRPID, testOrigin, testChallenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	t.Run("wrong origin", func(t *testing.T) {
+		response := authenticator.assert(t, testRPID, "https://evil.example", testChallenge)
+		if _, err := testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, 0); !errors.Is(err, ErrClientData) {
+			t.Fatalf("VerifyAssertion() error = %v, want %v", err, ErrClientData)
+		}
+	})
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		response := authenticator.assert(t, testRPID, testOrigin, "b3RoZXItY2hhbGxlbmdl")
+		if _, err := testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, 0); !errors.Is(err, ErrClientData) {
+			t.Fatalf("VerifyAssertion() error = %v, want %v", err, ErrClientData)
+		}
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		other := newSoftwareAuthenticator(t)
+		response := other.assert(t, testRPID, testOrigin, testChallenge)
+		if _, err := testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, 0); !errors.Is(err, ErrSignature) {
+			t.Fatalf("VerifyAssertion() error = %v, want %v", err, ErrSignature)
+		}
+	})
+
+	t.Run("tampered authenticator data", func(t *testing.T) {
+		response := authenticator.assert(t, testRPID, testOrigin, testChallenge)
+		authenticator.signCount += 10
+		response.Response.AuthenticatorData = EncodeBase64URL(authenticator.authenticatorData(testRPID, false))
+		if _, err := testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, 0); !errors.Is(err, ErrSignature) {
+			t.Fatalf("VerifyAssertion() error = %v, want %v", err, ErrSignature)
+		}
+	})
+
+	t.Run("sign count rollback", func(t *testing.T) {
+		response := authenticator.assert(t, testRPID, testOrigin, testChallenge)
+		for _, stored := range []uint32{authenticator.signCount, authenticator.signCount + 1} {
+			if _, err := testRP.VerifyAssertion(testChallenge, response, credential.PublicKey, stored); !errors.Is(err, ErrSignCountRollback) {
+				t.Fatalf("VerifyAssertion() with stored count %d error = %v, want %v", stored, err, ErrSignCountRollback)
+			}
+		}
+	})
+
+	t.Run("authenticator without a counter", func(t *testing.T) {
+		counterless := newSoftwareAuthenticator(t)
+		counterless.counterless = true
+		for i := 0; i < 2; i++ {
+			response := counterless.assert(t, testRPID, testOrigin, testChallenge)
+			signCount, err := testRP.VerifyAssertion(testChallenge, response, counterless.coseKey(), 0)
+			if err != nil || signCount != 0 {
+				t.Fatalf("VerifyAssertion() = %d, %v, want 0, nil", signCount, err)
+			}
+		}
+	})
+}
@@ -3,7 +3,11 @@ package utils
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"math/big"
 	"strings"
 	"time"
 	"unicode"
@@ -24,6 +28,34 @@ func EncodeHexMD5(params string) string {
 	return hex.EncodeToString(sumString[:])
 }
 
+// GenerateRandomToken returns a URL safe random token built from n random bytes
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateNumericCode returns a random code of the given number of decimal digits, for codes people type in
+func GenerateNumericCode(digits int) (string, error) {
+	code := make([]byte, digits)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token, used to store secrets we only need to compare
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func SplitName(fullName string) (firstName, lastName string) {
 	parts := strings.Fields(fullName) // split by whitespace
 